@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/theteacat/romanesgo/lib"
+)
+
+// animate renders every frame of a keyframed animation in one process,
+// reusing a single generator, e.g.
+//
+//	romanesgo animate -ff=multicorn -kf=keyframes.json -fn=frames/%04d.png
+//
+// where keyframes.json looks like
+//
+//	[
+//	  {"frame": 0, "zoom": 0.5, "constants": [1], "easing": "inout"},
+//	  {"frame": 100, "zoom": 0.5, "constants": [5]}
+//	]
+//
+// Keyframes without an x, y, zoom or constants take them from the flags, so
+// -x, -y, -z, -bounds, -rad and -pw frame any keyframe that doesn't say.
+func animate(args []string) {
+	fs := flag.NewFlagSet("animate", flag.ExitOnError)
+	rf := addRenderFlags(fs)
+	keyframesFile := fs.String("kf", "keyframes.json", "keyframes file (json)")
+	fn := fs.String("fn", "frame%04d.png", `filename, either a numbered png pattern (e.g. "frames/%04d.png"), a .gif or an animated .png`)
+	var enc encodeFlags
+	enc.add(fs)
+	fs.Parse(args)
+
+	fatal(rf.view())
+	if rf.clip != nil {
+		fatal(errors.New("-fit=letterbox can't be animated, as it clips a region that stays put while the view moves"))
+	}
+
+	keyframes, err := readKeyframes(*keyframesFile, lib.Keyframe{
+		X:         *rf.xCentre,
+		Y:         *rf.yCentre,
+		Zoom:      *rf.zoom,
+		Constants: rf.constants,
+	})
+	fatal(err)
+	if len(keyframes) == 0 {
+		fatal(lib.ErrNoKeyframes)
+	}
+
+	animation, err := lib.NewAnimation(keyframes)
+	fatal(err)
+
+	// Validate the fractal and colour scheme before rendering anything
+	_, err = rf.pointFunc(keyframes[0].Constants, rf.options())
+	fatal(err)
+
+	out, err := newFrameWriter(*fn, animation.Frames(), *rf.width, *rf.height, enc)
+	fatal(err)
+
+	rf.print()
+	fmt.Print("\tKeyframes (kf):\t\t", *keyframesFile,
+		"\n\tFrames:\t\t\t", animation.Frames(),
+		"\n\tFrame delay (delay):\t", *enc.delay,
+		"\n\tLoops (loop):\t\t", *enc.loops,
+		"\n\tFilename (fn):\t\t", *fn, "\n\n")
+
+	gen := rf.generator(nil)
+
+	timeIt(func() {
+		for frame := 0; frame < animation.Frames(); frame++ {
+			kf := animation.At(frame)
+
+			opts := rf.options()
+			opts.PaletteOffset += kf.Palette
+			pointFunc, err := rf.pointFunc(kf.Constants, opts)
+			fatal(err)
+
+			gen.SetPointFunc(pointFunc)
+			gen.SetView(kf.X, -kf.Y, kf.Zoom, *rf.rotation+kf.Rotation)
+			gen.Generate()
+
+			fatal(out.WriteFrame(frame, gen.Img))
+			fmt.Printf("\rFrame %d/%d", frame+1, animation.Frames())
+		}
+		fmt.Println()
+		fatal(out.Close())
+	})
+}
+
+// readKeyframes reads a keyframes file, filling in whatever a keyframe leaves
+// out from the defaults
+func readKeyframes(fn string, defaults lib.Keyframe) ([]lib.Keyframe, error) {
+	file, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	raw := []json.RawMessage{}
+	if err := json.NewDecoder(file).Decode(&raw); err != nil {
+		return nil, err
+	}
+	keyframes := make([]lib.Keyframe, len(raw))
+	for key := range raw {
+		keyframes[key] = defaults
+		if err := json.Unmarshal(raw[key], &keyframes[key]); err != nil {
+			return nil, err
+		}
+		if len(keyframes[key].Constants) == 0 {
+			keyframes[key].Constants = defaults.Constants
+		}
+	}
+	return keyframes, nil
+}
+
+// frameWriter is where the frames of an animation end up
+type frameWriter interface {
+	WriteFrame(frame int, img *image.NRGBA) error
+	Close() error
+}
+
+// encodeFlags are the flags for encoding an animation to a single file
+type encodeFlags struct {
+	delay   *int
+	loops   *int
+	palette *string
+	dither  *bool
+}
+
+func (enc *encodeFlags) add(fs *flag.FlagSet) {
+	enc.delay = fs.Int("delay", 4, "frame delay in hundredths of a second (gif & apng)")
+	enc.loops = fs.Int("loop", 0, "number of times the animation plays, 0 is forever (gif & apng)")
+	enc.palette = fs.String("pal", "shared", "gif palette: shared (one palette for every frame), frame (a palette per frame), plan9 or websafe")
+	enc.dither = fs.Bool("dither", true, "dither gif frames")
+}
+
+// newFrameWriter picks a frameWriter based upon the output filename
+func newFrameWriter(fn string, frames, width, height int, enc encodeFlags) (frameWriter, error) {
+	switch strings.ToLower(filepath.Ext(fn)) {
+	case ".gif":
+		return newGIFWriter(fn, enc)
+	case ".png", ".apng":
+		if strings.Contains(fn, "%") {
+			return pngSequenceWriter(fn), nil
+		}
+		return newAPNGWriter(fn, frames, width, height, enc)
+	}
+	if !strings.Contains(fn, "%") {
+		return nil, fmt.Errorf("%q is neither a .gif, a .png nor a numbered png pattern", fn)
+	}
+	return pngSequenceWriter(fn), nil
+}
+
+// pngSequenceWriter writes each frame to its own png, named by formatting the frame number into it
+type pngSequenceWriter string
+
+func (w pngSequenceWriter) WriteFrame(frame int, img *image.NRGBA) error {
+	file, err := os.Create(fmt.Sprintf(string(w), frame))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
+
+func (w pngSequenceWriter) Close() error {
+	return nil
+}
+
+// apngWriter streams frames straight out to an animated png
+type apngWriter struct {
+	file  *os.File
+	enc   *lib.APNGEncoder
+	delay int
+}
+
+func newAPNGWriter(fn string, frames, width, height int, enc encodeFlags) (*apngWriter, error) {
+	file, err := os.Create(fn)
+	if err != nil {
+		return nil, err
+	}
+	apng, err := lib.NewAPNGEncoder(file, width, height, frames, *enc.loops)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &apngWriter{file, apng, *enc.delay}, nil
+}
+
+func (w *apngWriter) WriteFrame(frame int, img *image.NRGBA) error {
+	return w.enc.Encode(img, w.delay)
+}
+
+func (w *apngWriter) Close() error {
+	err := w.enc.Close()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// gifWriter collects frames and writes them all out as an animated gif when closed
+type gifWriter struct {
+	fn      string
+	anim    *gif.GIF
+	palette color.Palette
+	dither  bool
+	delay   int
+
+	// With a shared palette, frames are kept until the palette can be built from all of them
+	shared bool
+	frames []*image.NRGBA
+}
+
+func newGIFWriter(fn string, enc encodeFlags) (*gifWriter, error) {
+	w := &gifWriter{
+		fn:     fn,
+		anim:   &gif.GIF{},
+		dither: *enc.dither,
+		delay:  *enc.delay,
+	}
+	if w.delay < 0 || w.delay > math.MaxUint16 {
+		return nil, fmt.Errorf("a gif's -delay must be from 0 to %d", math.MaxUint16)
+	}
+
+	// gif counts loops after the first play, with -1 meaning play once
+	switch *enc.loops {
+	case 0:
+		w.anim.LoopCount = 0
+	case 1:
+		w.anim.LoopCount = -1
+	default:
+		w.anim.LoopCount = *enc.loops - 1
+	}
+
+	switch strings.ToLower(*enc.palette) {
+	case "shared":
+		w.shared = true
+	case "frame":
+	case "plan9":
+		w.palette = palette.Plan9
+	case "websafe":
+		w.palette = palette.WebSafe
+	default:
+		return nil, fmt.Errorf("invalid gif palette %q", *enc.palette)
+	}
+	return w, nil
+}
+
+func (w *gifWriter) WriteFrame(frame int, img *image.NRGBA) error {
+	if w.shared {
+		// The generator reuses its image between frames, so keep a copy
+		frameCopy := image.NewNRGBA(img.Bounds())
+		copy(frameCopy.Pix, img.Pix)
+		w.frames = append(w.frames, frameCopy)
+		return nil
+	}
+
+	pal := w.palette
+	if pal == nil {
+		pal = lib.MedianCut([]*image.NRGBA{img}, 256)
+	}
+	w.addFrame(img, pal)
+	return nil
+}
+
+func (w *gifWriter) addFrame(img *image.NRGBA, pal color.Palette) {
+	paletted := image.NewPaletted(img.Bounds(), pal)
+	if w.dither {
+		draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, image.Point{})
+	} else {
+		draw.Draw(paletted, img.Bounds(), img, image.Point{}, draw.Src)
+	}
+	w.anim.Image = append(w.anim.Image, paletted)
+	w.anim.Delay = append(w.anim.Delay, w.delay)
+}
+
+func (w *gifWriter) Close() error {
+	if w.shared {
+		pal := lib.MedianCut(w.frames, 256)
+		for _, frame := range w.frames {
+			w.addFrame(frame, pal)
+		}
+		w.frames = nil
+	}
+
+	file, err := os.Create(w.fn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gif.EncodeAll(file, w.anim)
+}
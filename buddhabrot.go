@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+
+	"github.com/theteacat/romanesgo/lib"
+)
+
+// buddhabrot renders the buddhabrot, or the anti-buddhabrot, of a fractal
+// with a map: where the orbits of its points go, rather than how quickly they
+// escape. Different iteration limits for each channel make a nebulabrot, e.g.
+//
+//	romanesgo buddhabrot -ff=mandelbrot -ri=5000 -gi=500 -bi=50 -n=50000000 -fn=nebulabrot.png
+func buddhabrot(args []string) {
+	fs := flag.NewFlagSet("buddhabrot", flag.ExitOnError)
+	fractalName := fs.String("ff", "mandelbrot", "fractal, one of those with a map (mandelbrot, multibrot, burningship...)")
+	var constants flagConstants
+	fs.Var(&constants, "c", "constants")
+	params := flagParams{}
+	fs.Var(&params, "p", `named parameter, as "name=value" e.g. "power=3"`)
+	iterations := fs.Int("i", 1000, "maximum iterations, for any channel without its own")
+	redIterations := fs.Int("ri", 0, "maximum iterations of the red channel")
+	greenIterations := fs.Int("gi", 0, "maximum iterations of the green channel")
+	blueIterations := fs.Int("bi", 0, "maximum iterations of the blue channel")
+	minIterations := fs.Int("mi", 0, "leave out the orbits of points that escape in fewer iterations than this")
+	anti := fs.Bool("anti", false, "plot the orbits of points that don't escape, in lieu of those that do")
+	samples := fs.Int("n", 10000000, "number of points sampled")
+	seed := fs.Int64("seed", 0, "random seed")
+	toneMapName := fs.String("tone", "sqrt", "tone map: linear, sqrt or log")
+	exposure := fs.Float64("exp", 1, "exposure, which brightens the image before clipping it to white")
+	bailoutRadius := fs.Float64("br", 0, "bailout radius, or 0 for the fractal's own")
+	norm := flagNorm{name: "euclidean"}
+	fs.Var(&norm, "bn", "bailout norm: euclidean, manhattan, max, real or imag")
+	vf := addViewFlags(fs)
+	setDefault(fs, "x", "-0.5")
+	setDefault(fs, "z", "0.8")
+	fn := fs.String("fn", "buddhabrot.png", "filename")
+	fs.Parse(args)
+
+	if *samples < 1 {
+		fatal(errors.New("-n must be at least 1"))
+	}
+	fatal(vf.view())
+	if *vf.samples != 1 || vf.clip != nil {
+		fatal(errors.New("-ss and -fit=letterbox aren't supported by buddhabrot"))
+	}
+
+	limits := [3]int{*redIterations, *greenIterations, *blueIterations}
+	for channel := range limits {
+		if limits[channel] == 0 {
+			limits[channel] = *iterations
+		}
+		if limits[channel] < 1 {
+			fatal(errors.New("iteration limits must be at least 1"))
+		}
+	}
+
+	orbiter, err := lib.GetOrbiter(*fractalName, constants, lib.Options{Params: params, BailoutRadius: *bailoutRadius, BailoutNorm: norm.Norm})
+	fatal(err)
+	toneMap, err := lib.GetToneMap(*toneMapName)
+	fatal(err)
+
+	fmt.Print("\n\tFractal (ff):\t\t", *fractalName,
+		"\n\tConstants (c):\t\t", constants.String(),
+		"\n\tParameters (p):\t\t", params.String(),
+		"\n\tMax Iterations (r,g,b):\t", limits[0], ", ", limits[1], ", ", limits[2],
+		"\n\tMin Iterations (mi):\t", *minIterations,
+		"\n\tAnti (anti):\t\t", *anti,
+		"\n\tSamples (n):\t\t", *samples,
+		"\n\tSeed (seed):\t\t", *seed,
+		"\n\tTone map (tone):\t", *toneMapName,
+		"\n\tExposure (exp):\t\t", *exposure,
+		"\n\tCentre x Coord (x):\t", *vf.xCentre,
+		"\n\tCentre y Coord (y):\t", *vf.yCentre,
+		"\n\tZoom factor (z):\t", *vf.zoom,
+		"\n\tImage Width (w):\t", *vf.width,
+		"\n\tImage Height (h):\t", *vf.height,
+		"\n\tRoutines (r):\t\t", *vf.routines,
+		"\n\tFilename (png) (fn):\t", *fn, "\n\n")
+
+	gen := lib.NewDensityGenerator(*vf.width, *vf.height, *vf.routines, *samples, limits, *vf.xCentre, -*vf.yCentre, *vf.zoom, orbiter)
+	gen.SetAnti(*anti)
+	gen.SetMinIterations(*minIterations)
+	gen.SetSeed(*seed)
+	gen.SetToneMap(toneMap, *exposure)
+
+	newFile, err := os.Create(*fn)
+	fatal(err)
+
+	timeIt(func() {
+		gen.Generate()
+
+		err = png.Encode(newFile, gen.Img)
+		fatal(err)
+	})
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/theteacat/romanesgo/lib"
+)
+
+// exploreReservedRows are the rows of the terminal left for the status lines under the view
+const exploreReservedRows = 2
+
+// exploreZoomStep is how much + and - zoom by, which is a whole factor so
+// that the frame cache has pixels to reuse
+const exploreZoomStep = 2
+
+// exploreKeys are the keys, shown under the view until there's a message
+const exploreKeys = "arrows pan  +/- zoom  f/F fractal  c/C colour  [/] iterations  w write  b bookmark  q quit"
+
+// explore lets a fractal be explored in the terminal, e.g.
+//
+//	romanesgo explore -ff=mandelbrot -cf=smoothcolor
+//
+// The arrow keys pan, + and - zoom, f and F cycle through the fractals, c and
+// C through the colour schemes, and [ and ] halve and double the iterations.
+// w writes a full render of the view, and b adds the command line for it to
+// the bookmarks. Each view is rendered progressively, coarsely at first, and a
+// view that's left before it's done is abandoned, so the keys are never kept
+// waiting. What's already been rendered of a view is reused by the next.
+func explore(args []string) {
+	fs := flag.NewFlagSet("explore", flag.ExitOnError)
+	rf := addRenderFlags(fs)
+	fn := fs.String("fn", "explore%02d.png", "filename of full renders, numbered if it has a %d verb")
+	bookmarks := fs.String("bm", "bookmarks.txt", "file the command lines of bookmarked views are added to")
+	fs.Parse(args)
+
+	if *rf.fractalName == "none" && *rf.formula == "" {
+		fs.Set("ff", "mandelbrot")
+	}
+	fatal(rf.view())
+
+	ex := &explorer{
+		fs:        fs,
+		rf:        rf,
+		fn:        *fn,
+		bookmarks: *bookmarks,
+		cache:     lib.NewFrameCache(),
+		frames:    make(chan exploreFrame),
+		messages:  make(chan string),
+		done:      make(chan struct{}),
+	}
+	defer close(ex.done)
+	for name := range lib.Fractals {
+		ex.fractals = append(ex.fractals, name)
+	}
+	sort.Strings(ex.fractals)
+
+	/* From here on the view is just -x, -y, -z and -h, whatever framed it to
+	   begin with, so that's what bookmarks have.
+	*/
+	rf.clip = nil
+	ex.set("x", *rf.xCentre)
+	ex.set("y", *rf.yCentre)
+	ex.set("z", *rf.zoom)
+	fs.Set("h", strconv.Itoa(*rf.height))
+
+	restore, err := makeRaw()
+	fatal(err)
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	keys := make(chan []byte)
+	go readKeys(keys)
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+
+	ex.render()
+	for {
+		select {
+		case key, open := <-keys:
+			if !open || !ex.press(string(key)) {
+				if ex.cancel != nil {
+					ex.cancel()
+				}
+				return
+			}
+		case <-resize:
+			ex.render()
+		case frame := <-ex.frames:
+			if frame.generation == ex.generation {
+				ex.frame = frame.img
+				ex.draw()
+			}
+		case message := <-ex.messages:
+			ex.message = message
+			ex.draw()
+		}
+	}
+}
+
+// explorer is the state of romanesgo explore, which is only touched by the
+// loop handling keys. The view lives in the render flags.
+type explorer struct {
+	fs        *flag.FlagSet
+	rf        *renderFlags
+	fn        string
+	bookmarks string
+	fractals  []string
+	written   int
+
+	cache      *lib.FrameCache
+	generation int
+	cancel     context.CancelFunc
+	frames     chan exploreFrame
+	frame      *image.NRGBA
+	messages   chan string
+	message    string
+	done       chan struct{} // closed once explore returns
+}
+
+// exploreFrame is a frame of a view, which is stale unless its generation is the explorer's
+type exploreFrame struct {
+	generation int
+	img        *image.NRGBA
+}
+
+// readKeys sends what's read from stdin, a key or escape sequence at a time.
+// An escape sequence can be split across reads, so an unfinished one is held
+// on to until the rest of it arrives.
+func readKeys(keys chan<- []byte) {
+	buf := make([]byte, 16)
+	pending := []byte{}
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		pending = append(pending, buf[:n]...)
+		for {
+			size := keySize(pending)
+			if size == 0 {
+				break
+			}
+			keys <- append([]byte{}, pending[:size]...)
+			pending = pending[size:]
+		}
+	}
+}
+
+// keySize returns the length of the key or escape sequence at the start of
+// what's been read, or 0 if there's nothing there or it isn't finished
+func keySize(read []byte) int {
+	if len(read) == 0 {
+		return 0
+	}
+	if read[0] != '\x1b' {
+		_, size := utf8.DecodeRune(read)
+		return size
+	}
+	if len(read) == 1 {
+		return 0
+	}
+	if read[1] != '[' && read[1] != 'O' {
+		return 1
+	}
+	// A CSI or SS3 sequence runs up to a final byte from @ to ~
+	for key := 2; key < len(read); key++ {
+		if read[key] >= '@' && read[key] <= '~' {
+			return key + 1
+		}
+	}
+	return 0
+}
+
+// press acts on a key, returning false if it quits
+func (ex *explorer) press(key string) bool {
+	rf := ex.rf
+	step := 0.25 / *rf.zoom
+	ex.message = ""
+
+	switch key {
+	case "q", "\x03":
+		return false
+	case "\x1b[A":
+		ex.set("y", *rf.yCentre+step)
+	case "\x1b[B":
+		ex.set("y", *rf.yCentre-step)
+	case "\x1b[C":
+		ex.set("x", *rf.xCentre+step)
+	case "\x1b[D":
+		ex.set("x", *rf.xCentre-step)
+	case "+", "=":
+		ex.set("z", *rf.zoom*exploreZoomStep)
+	case "-", "_":
+		ex.set("z", *rf.zoom/exploreZoomStep)
+	case "[":
+		if *rf.iterations > 1 {
+			ex.fs.Set("i", strconv.Itoa(*rf.iterations/2))
+		}
+	case "]":
+		ex.fs.Set("i", strconv.Itoa(*rf.iterations*2))
+	case "f", "F":
+		ex.cycleFractal(key == "f")
+	case "c", "C":
+		ex.cycleColor(key == "c")
+	case "w":
+		ex.write()
+		ex.draw()
+		return true
+	case "b":
+		ex.bookmark()
+		ex.draw()
+		return true
+	default:
+		return true
+	}
+	ex.render()
+	return true
+}
+
+// set sets a float flag
+func (ex *explorer) set(name string, value float64) {
+	ex.fs.Set(name, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// cycleFractal moves on to the next or previous fractal, with its own
+// constants and colour scheme, as the old ones probably don't suit it
+func (ex *explorer) cycleFractal(forwards bool) {
+	rf := ex.rf
+	next := 0
+	for key, name := range ex.fractals {
+		if name == *rf.fractalName && *rf.formula == "" {
+			next = key + 1
+			if !forwards {
+				next = key - 1 + len(ex.fractals)
+			}
+		}
+	}
+	ex.fs.Set("ff", ex.fractals[next%len(ex.fractals)])
+	ex.fs.Set("fx", "")
+	ex.fs.Set("cf", "default")
+	rf.constants, rf.strs, rf.params, rf.juliaPoint = nil, nil, flagParams{}, flagPoint{}
+}
+
+// cycleColor moves on to the next or previous colour scheme of the fractal
+func (ex *explorer) cycleColor(forwards bool) {
+	rf := ex.rf
+	frac, exists := lib.Fractals[*rf.fractalName]
+	if !exists || *rf.formula != "" {
+		ex.message = "only the colour schemes of fractals from -ff can be cycled through"
+		return
+	}
+	names := append([]string{"default"}, frac.ColorSchemes...)
+	next := 0
+	for key, name := range names {
+		if name == *rf.colorName {
+			next = key + 1
+			if !forwards {
+				next = key - 1 + len(names)
+			}
+		}
+	}
+	ex.fs.Set("cf", names[next%len(names)])
+}
+
+// size returns the size of the view: the largest the terminal fits with the
+// aspect ratio of -w and -h, so full renders show the same region
+func (ex *explorer) size() (width, height int) {
+	cols, rows, _, _, err := terminalSize()
+	if err != nil || cols == 0 || rows == 0 {
+		cols, rows = defaultCols, defaultRows
+	}
+	if rows > exploreReservedRows {
+		rows -= exploreReservedRows
+	}
+	return fitSize(*ex.rf.width, *ex.rf.height, cols, 2*rows)
+}
+
+// render abandons the view being rendered, if there is one, and starts rendering the current view
+func (ex *explorer) render() {
+	if ex.cancel != nil {
+		ex.cancel()
+	}
+	ex.generation++
+
+	rf := ex.rf
+	pointFunc, err := rf.pointFunc(rf.constants, rf.options())
+	if err != nil {
+		ex.message = err.Error()
+		ex.draw()
+		return
+	}
+
+	width, height := ex.size()
+	gen := lib.NewGenerator(width, height, *rf.routines, *rf.iterations, *rf.samples, *rf.xCentre, -*rf.yCentre, *rf.zoom, pointFunc)
+	rf.transform(&gen)
+
+	/* Pixels the last view has in common with this one aren't rendered again.
+	   Everything but the view goes in the key, and the cache can move the
+	   centre a fraction of a pixel to line the views up.
+	*/
+	key := commandLine(ex.fs, "x", "y", "z", "w", "h", "fn", "bm", "r")
+	ex.cache.Prepare(key, &gen)
+	xPos, yPos, _, _ := gen.View()
+	ex.set("x", xPos)
+	ex.set("y", -yPos)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ex.cancel = cancel
+	go func(generation int) {
+		// The generator goes on writing to its image, so each frame is a copy
+		done := false
+		gen.GenerateProgressive(ctx, func(step int) bool {
+			img := *gen.Img
+			img.Pix = append([]uint8{}, gen.Img.Pix...)
+			select {
+			case ex.frames <- exploreFrame{generation, &img}:
+				done = step == 1
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if done {
+			ex.cache.Keep(key, gen)
+		}
+	}(ex.generation)
+}
+
+// draw draws the latest frame and the status lines under it
+func (ex *explorer) draw() {
+	rf := ex.rf
+	out := bufio.NewWriter(os.Stdout)
+	out.WriteString("\x1b[H")
+	if ex.frame != nil {
+		writeHalfBlocks(out, ex.frame)
+	}
+
+	name := *rf.fractalName
+	if *rf.formula != "" {
+		name = *rf.formula
+	}
+	fmt.Fprintf(out, "%s  %s  i=%d  x=%g  y=%g  z=%g\x1b[K\n", name, *rf.colorName, *rf.iterations, *rf.xCentre, *rf.yCentre, *rf.zoom)
+	if ex.message != "" {
+		out.WriteString(ex.message)
+	} else {
+		out.WriteString(exploreKeys)
+	}
+	out.WriteString("\x1b[K\x1b[J")
+	out.Flush()
+}
+
+// write starts writing a full render of the view, saying when it's done
+func (ex *explorer) write() {
+	rf := ex.rf
+	pointFunc, err := rf.pointFunc(rf.constants, rf.options())
+	if err != nil {
+		ex.message = err.Error()
+		return
+	}
+	ex.written++
+	fn := ex.fn
+	if strings.Contains(fn, "%") {
+		fn = fmt.Sprintf(fn, ex.written)
+	}
+	gen := rf.generator(pointFunc)
+	ex.message = "Writing " + fn + "..."
+
+	go func() {
+		start := time.Now()
+		gen.Generate()
+		file, err := os.Create(fn)
+		if err == nil {
+			err = png.Encode(file, gen.Img)
+			file.Close()
+		}
+		message := fmt.Sprint("Wrote ", fn, " in ", time.Since(start).Round(time.Millisecond))
+		if err != nil {
+			message = "Error: " + err.Error()
+		}
+		select {
+		case ex.messages <- message:
+		case <-ex.done:
+		}
+	}()
+}
+
+// bookmark adds the command line of the view to the bookmarks
+func (ex *explorer) bookmark() {
+	file, err := os.OpenFile(ex.bookmarks, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = fmt.Fprintln(file, commandLine(ex.fs, "fn", "bm", "bounds", "rad", "pw", "fit"))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		ex.message = "Error: " + err.Error()
+		return
+	}
+	ex.message = "Bookmarked in " + ex.bookmarks
+}
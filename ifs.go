@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/theteacat/romanesgo/lib"
+)
+
+// ifs renders an iterated function system or fractal flame by playing the
+// chaos game, either a built in one or one from a file, e.g.
+//
+//	romanesgo ifs -sys=fern -fn=fern.png
+//	romanesgo ifs -file=flame.json -n=50000000 -ss=2
+//
+// where flame.json looks like
+//
+//	{"transforms": [
+//	  {"coefs": [0.6, -0.4, 0.4, 0.6, 0.2, 0], "weight": 1, "color": 0, "variations": {"swirl": 1}},
+//	  {"coefs": [0.5, 0, 0, 0.5, -0.5, 0.3], "weight": 1, "color": 1, "variations": {"spherical": 0.6, "linear": 0.4}}
+//	]}
+//
+// Without -z, -bounds, -rad or -pw the whole attractor is fitted into the image.
+func ifs(args []string) {
+	systemNames, variationNames := []string{}, []string{}
+	for name := range lib.IFSs {
+		systemNames = append(systemNames, name)
+	}
+	for name := range lib.Variations {
+		variationNames = append(variationNames, name)
+	}
+	sort.Strings(systemNames)
+	sort.Strings(variationNames)
+
+	fs := flag.NewFlagSet("ifs", flag.ExitOnError)
+	systemName := fs.String("sys", "fern", "built in system: "+strings.Join(systemNames, ", "))
+	systemFile := fs.String("file", "", "system file (json) in lieu of -sys, with variations from: "+strings.Join(variationNames, ", "))
+	points := fs.Int("n", 10000000, "number of points plotted")
+	seed := fs.Int64("seed", 0, "random seed")
+	exposure := fs.Float64("exp", 1, "exposure, which brightens the image before clipping it to white")
+	gamma := fs.Float64("gamma", 2.2, "gamma, which brings out fainter detail")
+	paletteOffset := fs.Float64("po", 0, "palette offset")
+	vf := addViewFlags(fs)
+	fs.Lookup("z").Usage = "zoom factor, fitting the whole system into the image if neither it, -bounds, -rad nor -pw are given"
+	fn := fs.String("fn", "ifs.png", "filename")
+	fs.Parse(args)
+
+	if *gamma <= 0 {
+		fatal(errors.New("-gamma must be greater than 0"))
+	}
+
+	var system lib.IFS
+	var err error
+	if *systemFile != "" {
+		file, err := os.Open(*systemFile)
+		fatal(err)
+		err = json.NewDecoder(file).Decode(&system)
+		file.Close()
+		fatal(err)
+		*systemName = *systemFile
+	} else {
+		system, err = lib.GetIFS(*systemName)
+		fatal(err)
+	}
+
+	framed := false
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "z", "bounds", "rad", "pw":
+			framed = true
+		}
+	})
+	if !framed {
+		vf.bounds.Bounds, err = system.Bounds(*seed)
+		fatal(err)
+		vf.bounds.set = true
+	}
+	fatal(vf.view())
+	if vf.clip != nil {
+		fatal(errors.New("-fit=letterbox isn't supported by ifs"))
+	}
+
+	gen, err := lib.NewChaosGenerator(*vf.width, *vf.height, *vf.routines, *vf.samples, *points, *vf.xCentre, -*vf.yCentre, *vf.zoom, system)
+	fatal(err)
+	gen.SetSeed(*seed)
+	gen.SetToneMap(*exposure, *gamma)
+	gen.SetPaletteOffset(*paletteOffset)
+
+	fmt.Print("\n\tSystem (sys):\t\t", *systemName,
+		"\n\tPoints (n):\t\t", *points,
+		"\n\tSeed (seed):\t\t", *seed,
+		"\n\tExposure (exp):\t\t", *exposure,
+		"\n\tGamma (gamma):\t\t", *gamma,
+		"\n\tPalette offset (po):\t", *paletteOffset,
+		"\n\tCentre x Coord (x):\t", *vf.xCentre,
+		"\n\tCentre y Coord (y):\t", *vf.yCentre,
+		"\n\tZoom factor (z):\t", *vf.zoom,
+		"\n\tImage Width (w):\t", *vf.width,
+		"\n\tImage Height (h):\t", *vf.height,
+		"\n\tSupersampling (ss):\t", *vf.samples,
+		"\n\tRoutines (r):\t\t", *vf.routines,
+		"\n\tFilename (png) (fn):\t", *fn, "\n\n")
+
+	newFile, err := os.Create(*fn)
+	fatal(err)
+
+	timeIt(func() {
+		fatal(gen.Generate())
+
+		err = png.Encode(newFile, gen.Img)
+		fatal(err)
+	})
+}
@@ -0,0 +1,126 @@
+package lib
+
+import "math"
+
+// The burning ship, tricorn and their relatives are all the mandelbrot set
+// with absolute values taken of z's components, or z conjugated, before or
+// after it's raised to a power. absFold is a mask of which of those to do, so
+// one loop covers the whole family:
+//
+//	mandelbrot         0
+//	burning ship       foldReal | foldImag
+//	tricorn            foldConj
+//	celtic             foldPowReal
+//	buffalo            foldPowReal | foldPowImag
+//	perpendicular      foldReal | foldConj
+//	perpendicular ship foldImag | foldConj
+//	heart              foldReal
+type absFold int
+
+const (
+	foldReal    absFold = 1 << iota // |re(z)| before raising z to the power
+	foldImag                        // |im(z)| before raising z to the power
+	foldConj                        // conjugate z before raising it to the power
+	foldPowReal                     // |re(z)| after raising z to the power
+	foldPowImag                     // |im(z)| after raising z to the power
+
+	foldAll = foldReal | foldImag | foldConj | foldPowReal | foldPowImag
+)
+
+// absFoldMask is the mask parameter of the absfold fractals
+var absFoldMask = Param{
+	Name:    "mask",
+	Type:    ParamInt,
+	Default: "3",
+	Min:     0,
+	Max:     float64(foldAll),
+	Help:    "adds together 1 for the absolute value of z's real component and 2 for its imaginary component before raising z to the power, 4 to conjugate z before raising it to the power, and 8 and 16 for the absolute values of the real and imaginary components afterwards",
+}
+
+// absFoldMap returns the map z -> fold(z)^power + c
+func absFoldMap(fold absFold, power float64) func(z, c Complex) Complex {
+	pow := powerFunc(power)
+	return func(z, c Complex) Complex {
+		if fold&foldReal != 0 {
+			z.Real = math.Abs(z.Real)
+		}
+		if fold&foldImag != 0 {
+			z.Imag = math.Abs(z.Imag)
+		}
+		if fold&foldConj != 0 {
+			z.Imag = -z.Imag
+		}
+		z = pow(z)
+		if fold&foldPowReal != 0 {
+			z.Real = math.Abs(z.Real)
+		}
+		if fold&foldPowImag != 0 {
+			z.Imag = math.Abs(z.Imag)
+		}
+		return z.Add(c)
+	}
+}
+
+// absFoldFractal returns a PointFunc for an abs-folded mandelbrot set. If
+// julia is nil c is the point being rendered and z starts at 0, otherwise c
+// is *julia and z starts at the point being rendered.
+func absFoldFractal(color ColorFunc, bailout Bailout, fold absFold, power float64, julia *Complex) PointFunc {
+	fn := absFoldMap(fold, power)
+	return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+		c, z := Complex{xCoord, yCoord}, Complex{0, 0}
+		if julia != nil {
+			c, z = *julia, Complex{xCoord, yCoord}
+		}
+		iterations := 0
+
+		iterate := func(z Complex) Complex {
+			return fn(z, c)
+		}
+
+		for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
+			z = iterate(z)
+		}
+
+		return color(
+			iterations,
+			iterationCap,
+			map[string]interface{}{
+				"z":        z,
+				"iterator": iterate,
+				"bailout":  bailout,
+			},
+		)
+	}
+}
+
+// absFoldVariant returns a Fractal for one member of the family, with the power as its parameter
+func absFoldVariant(description string, fold absFold, julia string) *Fractal {
+	return &Fractal{
+		Description:        description,
+		Params:             []Param{powerParam2},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Julia:              julia,
+		Map: func(params Params) func(z, c Complex) Complex {
+			return absFoldMap(fold, params.Float("power"))
+		},
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			return absFoldFractal(color, bailout, fold, params.Float("power"), nil)
+		},
+	}
+}
+
+// absFoldJuliaVariant returns a Fractal for the julia sets of one member of
+// the family, with c and the power as its parameters
+func absFoldJuliaVariant(description string, fold absFold) *Fractal {
+	return &Fractal{
+		Description:        description,
+		Params:             []Param{juliaC, powerParam2},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
+			return absFoldFractal(color, bailout, fold, params.Float("power"), &c)
+		},
+	}
+}
@@ -0,0 +1,166 @@
+package lib
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Named errors for validating animations
+var (
+	ErrInvalidEasing    = errors.New("invalid easing curve name")
+	ErrNoKeyframes      = errors.New("an animation needs at least one keyframe")
+	ErrKeyframeOrder    = errors.New("keyframes must have strictly increasing frame numbers, starting from 0")
+	ErrKeyframeZoom     = errors.New("keyframe zoom factors must be greater than 0")
+	ErrKeyframeConstant = errors.New("every keyframe must have the same number of constants")
+)
+
+// Easing maps linear progress between two keyframes, from 0 to 1, onto eased progress.
+type Easing func(t float64) float64
+
+// Easings is a map of the available easing curves
+var Easings = map[string]Easing{
+	"linear": func(t float64) float64 {
+		return t
+	},
+	"in": func(t float64) float64 {
+		return t * t * t
+	},
+	"out": func(t float64) float64 {
+		return 1 - math.Pow(1-t, 3)
+	},
+	"inout": func(t float64) float64 {
+		if t < 0.5 {
+			return 4 * t * t * t
+		}
+		return 1 - math.Pow(2-2*t, 3)/2
+	},
+	"smooth": func(t float64) float64 {
+		return t * t * (3 - 2*t)
+	},
+	"step": func(t float64) float64 {
+		return 0
+	},
+}
+
+// GetEasing returns an easing curve if the name is valid. The empty string is linear.
+func GetEasing(name string) (Easing, error) {
+	if name == "" {
+		name = "linear"
+	}
+	easing, validEasing := Easings[strings.ToLower(name)]
+	if !validEasing {
+		return nil, ErrInvalidEasing
+	}
+	return easing, nil
+}
+
+// Keyframe is the view at a given frame of an animation. Y is in the same
+// orientation as the CLI's -y flag.
+type Keyframe struct {
+	Frame     int       `json:"frame"`
+	X         float64   `json:"x"`
+	Y         float64   `json:"y"`
+	Zoom      float64   `json:"zoom"`
+	Rotation  float64   `json:"rotation"`
+	Constants []float64 `json:"constants"`
+	Palette   float64   `json:"palette"`
+	// Easing is the curve used to get from this keyframe to the next one.
+	Easing string `json:"easing"`
+}
+
+// Animation interpolates the view between a set of keyframes
+type Animation struct {
+	keyframes []Keyframe
+	easings   []Easing
+}
+
+// NewAnimation checks a set of keyframes and returns an animation of them
+func NewAnimation(keyframes []Keyframe) (*Animation, error) {
+	if len(keyframes) == 0 {
+		return nil, ErrNoKeyframes
+	}
+
+	keyframes = append([]Keyframe(nil), keyframes...)
+	sort.SliceStable(keyframes, func(i, j int) bool {
+		return keyframes[i].Frame < keyframes[j].Frame
+	})
+
+	if keyframes[0].Frame != 0 {
+		return nil, ErrKeyframeOrder
+	}
+
+	easings := make([]Easing, len(keyframes))
+	for key, kf := range keyframes {
+		if key > 0 && kf.Frame == keyframes[key-1].Frame {
+			return nil, ErrKeyframeOrder
+		}
+		if kf.Zoom <= 0 {
+			return nil, ErrKeyframeZoom
+		}
+		if len(kf.Constants) != len(keyframes[0].Constants) {
+			return nil, ErrKeyframeConstant
+		}
+
+		easing, err := GetEasing(kf.Easing)
+		if err != nil {
+			return nil, err
+		}
+		easings[key] = easing
+	}
+
+	return &Animation{keyframes, easings}, nil
+}
+
+// Frames returns the number of frames in the animation
+func (a *Animation) Frames() int {
+	return a.keyframes[len(a.keyframes)-1].Frame + 1
+}
+
+// At returns the interpolated view at the given frame
+func (a *Animation) At(frame int) Keyframe {
+	// Find the last keyframe at or before this frame
+	key := sort.Search(len(a.keyframes), func(i int) bool {
+		return a.keyframes[i].Frame > frame
+	}) - 1
+
+	if key < 0 {
+		return a.keyframes[0]
+	}
+	if key == len(a.keyframes)-1 {
+		return a.keyframes[key]
+	}
+
+	from, to := a.keyframes[key], a.keyframes[key+1]
+	t := a.easings[key](float64(frame-from.Frame) / float64(to.Frame-from.Frame))
+
+	kf := Keyframe{
+		Frame:     frame,
+		Rotation:  lerp(from.Rotation, to.Rotation, t),
+		Palette:   lerp(from.Palette, to.Palette, t),
+		Constants: make([]float64, len(from.Constants)),
+		Easing:    from.Easing,
+	}
+
+	// Zooming is interpolated logarithmically so that it appears to happen at a constant rate.
+	kf.Zoom = math.Exp(lerp(math.Log(from.Zoom), math.Log(to.Zoom), t))
+
+	/* Panning linearly whilst zooming exponentially makes a zoom target drift
+	   out of frame and back again. Instead, the centre moves in proportion to
+	   the change in the width of the view, which keeps the point being zoomed
+	   into still on screen.
+	*/
+	centreT := t
+	if from.Zoom != to.Zoom {
+		centreT = (1/kf.Zoom - 1/from.Zoom) / (1/to.Zoom - 1/from.Zoom)
+	}
+	kf.X = lerp(from.X, to.X, centreT)
+	kf.Y = lerp(from.Y, to.Y, centreT)
+
+	for key := range kf.Constants {
+		kf.Constants[key] = lerp(from.Constants[key], to.Constants[key], t)
+	}
+
+	return kf
+}
@@ -0,0 +1,271 @@
+package lib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// Named errors for animated png encoding
+var (
+	ErrAPNGFrameSize  = errors.New("apng frames must all be the size given to NewAPNGEncoder")
+	ErrAPNGFrameCount = errors.New("apng frame count differs from the count given to NewAPNGEncoder")
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// APNGEncoder streams frames out to an animated png, one at a time, so a
+// whole animation never has to be held in memory. Frames are always encoded
+// as 8 bit RGBA.
+type APNGEncoder struct {
+	w       io.Writer
+	width   int
+	height  int
+	frames  int
+	written int
+	seq     uint32
+
+	// Reused between frames
+	prev  []byte
+	cur   []byte
+	best  []byte
+	trial []byte
+	data  bytes.Buffer
+}
+
+// NewAPNGEncoder writes the header of an animated png of the given size and
+// number of frames. plays is the number of times the animation is played, 0
+// being forever.
+func NewAPNGEncoder(w io.Writer, width, height, frames, plays int) (*APNGEncoder, error) {
+	e := &APNGEncoder{
+		w:      w,
+		width:  width,
+		height: height,
+		frames: frames,
+		prev:   make([]byte, 4*width),
+		cur:    make([]byte, 4*width),
+		best:   make([]byte, 1+4*width),
+		trial:  make([]byte, 1+4*width),
+	}
+
+	if _, err := w.Write(pngSignature); err != nil {
+		return nil, err
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // color type: truecolor with alpha
+	if err := e.writeChunk("IHDR", ihdr); err != nil {
+		return nil, err
+	}
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:4], uint32(frames))
+	binary.BigEndian.PutUint32(actl[4:8], uint32(plays))
+	if err := e.writeChunk("acTL", actl); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Encode writes the next frame of the animation, to be shown for delay
+// hundredths of a second.
+func (e *APNGEncoder) Encode(img image.Image, delay int) error {
+	bounds := img.Bounds()
+	if bounds.Dx() != e.width || bounds.Dy() != e.height {
+		return ErrAPNGFrameSize
+	}
+	if e.written == e.frames {
+		return ErrAPNGFrameCount
+	}
+
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:4], e.seq)
+	binary.BigEndian.PutUint32(fctl[4:8], uint32(e.width))
+	binary.BigEndian.PutUint32(fctl[8:12], uint32(e.height))
+	// x and y offsets are both 0
+	num, den := apngDelay(delay)
+	binary.BigEndian.PutUint16(fctl[20:22], num)
+	binary.BigEndian.PutUint16(fctl[22:24], den)
+	// dispose op and blend op are both 0: none and source
+	e.seq++
+	if err := e.writeChunk("fcTL", fctl); err != nil {
+		return err
+	}
+
+	data, err := e.compress(img)
+	if err != nil {
+		return err
+	}
+
+	// The first frame is the default image, the rest go in frame data chunks
+	if e.written == 0 {
+		err = e.writeChunk("IDAT", data)
+	} else {
+		fdat := make([]byte, 4+len(data))
+		binary.BigEndian.PutUint32(fdat[0:4], e.seq)
+		copy(fdat[4:], data)
+		e.seq++
+		err = e.writeChunk("fdAT", fdat)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.written++
+	return nil
+}
+
+// apngDelay returns a delay in hundredths of a second as the fraction of a
+// second fcTL takes. Its numerator only has 16 bits, so long delays are given
+// in tenths or whole seconds, and the longest are cut short.
+func apngDelay(delay int) (num, den uint16) {
+	if delay < 0 {
+		delay = 0
+	}
+	denominator := 100
+	for delay > math.MaxUint16 && denominator > 1 {
+		delay, denominator = (delay+5)/10, denominator/10
+	}
+	if delay > math.MaxUint16 {
+		delay = math.MaxUint16
+	}
+	return uint16(delay), uint16(denominator)
+}
+
+// Close finishes the file. It does not close the underlying writer.
+func (e *APNGEncoder) Close() error {
+	if e.written != e.frames {
+		return ErrAPNGFrameCount
+	}
+	return e.writeChunk("IEND", nil)
+}
+
+func (e *APNGEncoder) writeChunk(name string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], name)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:8])
+	crc.Write(data)
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+
+	for _, b := range [][]byte{header, data, footer} {
+		if _, err := e.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compress filters and deflates a frame's scanlines
+func (e *APNGEncoder) compress(img image.Image) ([]byte, error) {
+	e.data.Reset()
+	zw, err := zlib.NewWriterLevel(&e.data, zlib.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range e.prev {
+		e.prev[i] = 0
+	}
+
+	bounds := img.Bounds()
+	nrgba, isNRGBA := img.(*image.NRGBA)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		if isNRGBA {
+			offset := nrgba.PixOffset(bounds.Min.X, y)
+			copy(e.cur, nrgba.Pix[offset:offset+4*e.width])
+		} else {
+			for x := 0; x < e.width; x++ {
+				c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, y)).(color.NRGBA)
+				e.cur[4*x], e.cur[4*x+1], e.cur[4*x+2], e.cur[4*x+3] = c.R, c.G, c.B, c.A
+			}
+		}
+
+		if _, err := zw.Write(e.filter()); err != nil {
+			return nil, err
+		}
+		e.prev, e.cur = e.cur, e.prev
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return e.data.Bytes(), nil
+}
+
+// filter picks whichever png filter type gives the smallest sum of absolute
+// differences for the current scanline, the same heuristic as image/png.
+func (e *APNGEncoder) filter() []byte {
+	bestSum := -1
+	for filterType := byte(0); filterType < 5; filterType++ {
+		e.trial[0] = filterType
+		sum := 0
+		for i, c := range e.cur {
+			var a, b, d byte
+			if i >= 4 {
+				a, d = e.cur[i-4], e.prev[i-4]
+			}
+			b = e.prev[i]
+
+			var predicted byte
+			switch filterType {
+			case 1:
+				predicted = a
+			case 2:
+				predicted = b
+			case 3:
+				predicted = byte((int(a) + int(b)) / 2)
+			case 4:
+				predicted = paeth(a, b, d)
+			}
+
+			e.trial[i+1] = c - predicted
+			sum += abs8(e.trial[i+1])
+		}
+
+		if bestSum < 0 || sum < bestSum {
+			bestSum = sum
+			e.best, e.trial = e.trial, e.best
+		}
+	}
+	return e.best
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := absInt(p-int(a)), absInt(p-int(b)), absInt(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	} else if pb <= pc {
+		return b
+	}
+	return c
+}
+
+// abs8 treats a filtered byte as signed, as image/png does when picking a filter
+func abs8(b byte) int {
+	if b < 128 {
+		return int(b)
+	}
+	return 256 - int(b)
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
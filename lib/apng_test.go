@@ -0,0 +1,195 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+// apngChunk is a chunk read back from an encoded png
+type apngChunk struct {
+	name string
+	data []byte
+}
+
+// readChunks splits an encoded png into its chunks, checking their CRCs
+func readChunks(t *testing.T, encoded []byte) []apngChunk {
+	t.Helper()
+	if !bytes.HasPrefix(encoded, pngSignature) {
+		t.Fatal("the png signature is missing")
+	}
+	chunks := []apngChunk{}
+	for rest := encoded[len(pngSignature):]; len(rest) > 0; {
+		if len(rest) < 12 {
+			t.Fatalf("%d bytes are left over after the last chunk", len(rest))
+		}
+		length := int(binary.BigEndian.Uint32(rest[0:4]))
+		chunk := apngChunk{string(rest[4:8]), rest[8 : 8+length]}
+		if crc32.ChecksumIEEE(rest[4:8+length]) != binary.BigEndian.Uint32(rest[8+length:12+length]) {
+			t.Errorf("the %s chunk's CRC is wrong", chunk.name)
+		}
+		chunks = append(chunks, chunk)
+		rest = rest[12+length:]
+	}
+	return chunks
+}
+
+// testFrames returns frames that differ, with transparency, and one that
+// isn't an NRGBA image
+func testFrames(width, height int) []image.Image {
+	frames := []image.Image{}
+	for frame := 0; frame < 3; frame++ {
+		img := image.NewNRGBA(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				img.SetNRGBA(x, y, color.NRGBA{uint8(x * 7 * (frame + 1)), uint8(y * 11), uint8((x + y) * frame), uint8(255 - x*y%200)})
+			}
+		}
+		frames = append(frames, img)
+	}
+	gray := image.NewGray(image.Rect(0, 0, width, height))
+	for key := range gray.Pix {
+		gray.Pix[key] = uint8(key * 13)
+	}
+	return append(frames, gray)
+}
+
+// toNRGBA converts an image to NRGBA, for comparing pixels
+func toNRGBA(img image.Image) *image.NRGBA {
+	nrgba := image.NewNRGBA(img.Bounds())
+	draw.Draw(nrgba, nrgba.Rect, img, img.Bounds().Min, draw.Src)
+	return nrgba
+}
+
+func TestAPNGEncoder(t *testing.T) {
+	width, height := 37, 23
+	frames := testFrames(width, height)
+	var buf bytes.Buffer
+	e, err := NewAPNGEncoder(&buf, width, height, len(frames), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, frame := range frames {
+		if err := e.Encode(frame, 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decoders that don't know about apngs see the first frame
+	decoded, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(toNRGBA(decoded).Pix, toNRGBA(frames[0]).Pix) {
+		t.Error("the default image isn't the first frame")
+	}
+
+	chunks := readChunks(t, buf.Bytes())
+	names := []string{"IHDR", "acTL"}
+	for frame := range frames {
+		names = append(names, "fcTL", "fdAT")
+		if frame == 0 {
+			names[len(names)-1] = "IDAT"
+		}
+	}
+	names = append(names, "IEND")
+	if len(chunks) != len(names) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(names))
+	}
+	for key, chunk := range chunks {
+		if chunk.name != names[key] {
+			t.Fatalf("chunk %d is %s, want %s", key, chunk.name, names[key])
+		}
+	}
+	if got := binary.BigEndian.Uint32(chunks[1].data[0:4]); got != uint32(len(frames)) {
+		t.Errorf("acTL has %d frames, want %d", got, len(frames))
+	}
+	if got := binary.BigEndian.Uint32(chunks[1].data[4:8]); got != 2 {
+		t.Errorf("acTL has %d plays, want 2", got)
+	}
+
+	// fcTL and fdAT chunks share one sequence, which counts up from 0
+	seq := uint32(0)
+	frame := 0
+	for _, chunk := range chunks {
+		switch chunk.name {
+		case "fcTL":
+			if got := binary.BigEndian.Uint32(chunk.data[0:4]); got != seq {
+				t.Errorf("an fcTL's sequence number is %d, want %d", got, seq)
+			}
+			seq++
+		case "fdAT":
+			if got := binary.BigEndian.Uint32(chunk.data[0:4]); got != seq {
+				t.Errorf("an fdAT's sequence number is %d, want %d", got, seq)
+			}
+			seq++
+
+			// A frame's data is the same as a png's, so it can be decoded as one
+			frame++
+			var single bytes.Buffer
+			single.Write(pngSignature)
+			w := &APNGEncoder{w: &single}
+			w.writeChunk("IHDR", chunks[0].data)
+			w.writeChunk("IDAT", chunk.data[4:])
+			w.writeChunk("IEND", nil)
+			decoded, err := png.Decode(&single)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(toNRGBA(decoded).Pix, toNRGBA(frames[frame]).Pix) {
+				t.Errorf("frame %d isn't the image encoded", frame)
+			}
+		}
+	}
+}
+
+func TestAPNGEncoderErrors(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := NewAPNGEncoder(&buf, 4, 4, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); !errors.Is(err, ErrAPNGFrameCount) {
+		t.Errorf("closing before every frame is written got %v, want %v", err, ErrAPNGFrameCount)
+	}
+	if err := e.Encode(image.NewNRGBA(image.Rect(0, 0, 4, 5)), 1); !errors.Is(err, ErrAPNGFrameSize) {
+		t.Errorf("a frame of the wrong size got %v, want %v", err, ErrAPNGFrameSize)
+	}
+	if err := e.Encode(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Encode(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 1); !errors.Is(err, ErrAPNGFrameCount) {
+		t.Errorf("a frame too many got %v, want %v", err, ErrAPNGFrameCount)
+	}
+}
+
+func TestAPNGDelay(t *testing.T) {
+	tests := []struct {
+		delay    int
+		num, den uint16
+	}{
+		{4, 4, 100},
+		{0, 0, 100},
+		{-3, 0, 100},
+		{65535, 65535, 100},
+		{65536, 6554, 10},
+		{100000, 10000, 10},
+		{1000000, 10000, 1},
+		{6553500, 65535, 1},
+		{100000000, 65535, 1},
+	}
+	for _, test := range tests {
+		if num, den := apngDelay(test.delay); num != test.num || den != test.den {
+			t.Errorf("a delay of %d is %d/%d, want %d/%d", test.delay, num, den, test.num, test.den)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package lib
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// ErrInvalidNorm is returned for an unknown bailout norm name
+var ErrInvalidNorm = errors.New("invalid bailout norm name")
+
+// Norm is the measure of the size of z that's compared against the bailout radius
+type Norm int
+
+// The available norms
+const (
+	Euclidean Norm = iota
+	Manhattan
+	MaxNorm
+	RealNorm
+	ImagNorm
+)
+
+// Norms is a map of the available norms by name
+var Norms = map[string]Norm{
+	"euclidean": Euclidean,
+	"manhattan": Manhattan,
+	"max":       MaxNorm,
+	"real":      RealNorm,
+	"imag":      ImagNorm,
+}
+
+// GetNorm returns a norm if the name is valid. The empty string is euclidean.
+func GetNorm(name string) (Norm, error) {
+	if name == "" {
+		return Euclidean, nil
+	}
+	norm, validNorm := Norms[strings.ToLower(name)]
+	if !validNorm {
+		return 0, ErrInvalidNorm
+	}
+	return norm, nil
+}
+
+// DefaultBailoutRadius is the bailout radius of fractals that don't set their own
+const DefaultBailoutRadius = 2
+
+// Bailout decides when z has escaped
+type Bailout struct {
+	Radius float64
+	Norm   Norm
+}
+
+// Size measures z with the bailout's norm
+func (b Bailout) Size(z Complex) float64 {
+	switch b.Norm {
+	case Manhattan:
+		return math.Abs(z.Real) + math.Abs(z.Imag)
+	case MaxNorm:
+		return math.Max(math.Abs(z.Real), math.Abs(z.Imag))
+	case RealNorm:
+		return math.Abs(z.Real)
+	case ImagNorm:
+		return math.Abs(z.Imag)
+	}
+	return z.Abs()
+}
+
+// Escaped checks if z has passed the bailout radius
+func (b Bailout) Escaped(z Complex) bool {
+	return b.Size(z) > b.Radius
+}
@@ -0,0 +1,269 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// chaosWarmup is how many times a point is mapped before it's plotted, so it
+// has settled onto the attractor
+const chaosWarmup = 20
+
+// chaosFitPoints is how many points are used to find an attractor's bounds,
+// and chaosFitOutliers the fraction of them at each edge that might be outliers
+const (
+	chaosFitPoints   = 100000
+	chaosFitOutliers = 0.005
+)
+
+// Bounds plays the chaos game to find the region of the plane the system's
+// attractor covers, leaving out the furthest few points, which some flames
+// scatter a long way away. Y is in the same orientation as the CLI's -y flag.
+func (ifs IFS) Bounds(seed int64) (Bounds, error) {
+	transforms, err := ifs.compile()
+	if err != nil {
+		return Bounds{}, err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	xs, ys := make([]float64, 0, chaosFitPoints), make([]float64, 0, chaosFitPoints)
+	x, y := 2*rng.Float64()-1, 2*rng.Float64()-1
+	resets := 0
+	for key := 0; len(xs) < chaosFitPoints; key++ {
+		x, y = pick(transforms, rng).apply(x, y)
+		if !finite(x, y) {
+			if resets++; resets > chaosFitPoints {
+				return Bounds{}, ErrNoAttractor
+			}
+			x, y, key = 2*rng.Float64()-1, 2*rng.Float64()-1, -1
+			continue
+		}
+		if key >= chaosWarmup {
+			xs, ys = append(xs, x), append(ys, y)
+		}
+	}
+	sort.Float64s(xs)
+	sort.Float64s(ys)
+
+	var b Bounds
+	b.XMin, b.XMax = fitRange(xs)
+	b.YMin, b.YMax = fitRange(ys)
+
+	// Leave a margin, and some width or height to lines
+	margin := math.Max(b.XMax-b.XMin, b.YMax-b.YMin) * 0.05
+	if margin == 0 {
+		margin = 1
+	}
+	return Bounds{b.XMin - margin, b.XMax + margin, b.YMin - margin, b.YMax + margin}, nil
+}
+
+// fitRange returns the range of the sorted values, leaving out outliers: those
+// further than a quarter of the range of the rest beyond it
+func fitRange(values []float64) (min, max float64) {
+	outliers := int(chaosFitOutliers * float64(len(values)))
+	min, max = values[outliers], values[len(values)-1-outliers]
+	span := max - min
+	return math.Max(values[0], min-span/4), math.Min(values[len(values)-1], max+span/4)
+}
+
+// finite checks neither coordinate of a point is infinite or NaN
+func finite(x, y float64) bool {
+	return !math.IsInf(x, 0) && !math.IsNaN(x) && !math.IsInf(y, 0) && !math.IsNaN(y)
+}
+
+// ChaosGenerator is our runner for iterated function systems and flames!
+type ChaosGenerator struct {
+	Img           *image.NRGBA
+	xPos          float64
+	yPos          float64
+	zoom          float64
+	scaler        float64
+	width         int
+	height        int
+	routines      int
+	samples       int
+	points        int
+	transforms    []chaosTransform
+	seed          int64
+	exposure      float64
+	gamma         float64
+	paletteOffset float64
+}
+
+// NewChaosGenerator returns a chaos game generator for the system, which plots
+// the given number of points. Points are binned at the supersampling factor
+// times the image size, and tone mapped before they're averaged down.
+func NewChaosGenerator(width, height, routines, samples, points int, xPos, yPos, zoom float64, ifs IFS) (ChaosGenerator, error) {
+	transforms, err := ifs.compile()
+	if err != nil {
+		return ChaosGenerator{}, err
+	}
+	return ChaosGenerator{
+		image.NewNRGBA(image.Rect(0, 0, width, height)),
+		xPos,
+		yPos,
+		zoom,
+		scaler(width, height),
+		width,
+		height,
+		routines,
+		samples,
+		points,
+		transforms,
+		0,
+		1,
+		1,
+		0,
+	}, nil
+}
+
+// SetSeed sets the seed the chaos game is played with
+func (f *ChaosGenerator) SetSeed(seed int64) {
+	f.seed = seed
+}
+
+// SetToneMap sets how the log of the density is mapped to brightness. The
+// exposure scales the brightness before it's clipped to white, and the gamma
+// brings out fainter detail.
+func (f *ChaosGenerator) SetToneMap(exposure, gamma float64) {
+	f.exposure = exposure
+	f.gamma = gamma
+}
+
+// SetPaletteOffset shifts the transforms' colors around the palette
+func (f *ChaosGenerator) SetPaletteOffset(offset float64) {
+	f.paletteOffset = offset
+}
+
+// chaosHistogram is how many points landed in each bin, and the sums of their colors
+type chaosHistogram struct {
+	width, height int
+	counts        []uint64
+	sums          [3][]uint64
+}
+
+// Generate spins out our workers, and tone maps what they find. It returns
+// ErrNoAttractor if the points keep escaping to infinity.
+func (f ChaosGenerator) Generate() error {
+	hist := chaosHistogram{width: f.width * f.samples, height: f.height * f.samples}
+	hist.counts = make([]uint64, hist.width*hist.height)
+	for channel := range hist.sums {
+		hist.sums[channel] = make([]uint64, hist.width*hist.height)
+	}
+
+	chunks := (f.points + densityChunk - 1) / densityChunk
+	next := int64(-1)
+	escaped := int32(0)
+
+	var wg sync.WaitGroup
+	wg.Add(f.routines)
+	for rno := 0; rno < f.routines; rno++ {
+		go func() {
+			defer wg.Done()
+			for chunk := int(atomic.AddInt64(&next, 1)); chunk < chunks; chunk = int(atomic.AddInt64(&next, 1)) {
+				points := densityChunk
+				if chunk == chunks-1 {
+					points = f.points - chunk*densityChunk
+				}
+				if !f.play(hist, rand.New(rand.NewSource(f.seed+int64(chunk))), points) {
+					atomic.StoreInt32(&escaped, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if escaped != 0 {
+		return ErrNoAttractor
+	}
+
+	f.toneMapInto(hist)
+	return nil
+}
+
+// play plays the chaos game from a random point, adding the points it visits
+// to the histogram, and returns false if it gives up as they keep escaping
+func (f ChaosGenerator) play(hist chaosHistogram, rng *rand.Rand, points int) bool {
+	pixelSize := (2 / f.scaler) / f.zoom
+
+	x, y, c := 2*rng.Float64()-1, 2*rng.Float64()-1, rng.Float64()
+	settled, resets := 0, 0
+	for plotted := 0; plotted < points; {
+		t := pick(f.transforms, rng)
+		x, y = t.apply(x, y)
+		c = (c + t.color) / 2
+
+		// Some variations send points off to infinity, so those start again
+		if !finite(x, y) {
+			if resets++; resets > points {
+				return false
+			}
+			x, y, settled = 2*rng.Float64()-1, 2*rng.Float64()-1, 0
+			continue
+		}
+		if settled < chaosWarmup {
+			settled++
+			continue
+		}
+		plotted++
+
+		// The plane's y axis goes up the image, like the CLI's -y flag
+		xBin := int(math.Floor(((x-f.xPos)/pixelSize + float64(f.width)/2 + 0.5) * float64(f.samples)))
+		yBin := int(math.Floor(((-y-f.yPos)/pixelSize + float64(f.height)/2 + 0.5) * float64(f.samples)))
+		if xBin < 0 || xBin >= hist.width || yBin < 0 || yBin >= hist.height {
+			continue
+		}
+
+		bin := yBin*hist.width + xBin
+		R, G, B := hsv(c+f.paletteOffset, 0.8, 1)
+		atomic.AddUint64(&hist.counts[bin], 1)
+		atomic.AddUint64(&hist.sums[0][bin], uint64(R))
+		atomic.AddUint64(&hist.sums[1][bin], uint64(G))
+		atomic.AddUint64(&hist.sums[2][bin], uint64(B))
+	}
+	return true
+}
+
+// toneMapInto maps the log density of each bin to its brightness, times the
+// average color of the points in it, and averages the bins down into the image
+func (f ChaosGenerator) toneMapInto(hist chaosHistogram) {
+	max := 0.0
+	for _, count := range hist.counts {
+		max = math.Max(max, float64(count))
+	}
+	if max == 0 {
+		max = 1
+	}
+
+	for yPix := 0; yPix < f.height; yPix++ {
+		for xPix := 0; xPix < f.width; xPix++ {
+			var rgb [3]float64
+			for ySub := 0; ySub < f.samples; ySub++ {
+				for xSub := 0; xSub < f.samples; xSub++ {
+					bin := (yPix*f.samples+ySub)*hist.width + xPix*f.samples + xSub
+					count := float64(hist.counts[bin])
+					if count == 0 {
+						continue
+					}
+					brightness := math.Min(math.Log1p(count)/math.Log1p(max)*f.exposure, 1)
+					brightness = math.Pow(brightness, 1/f.gamma)
+					for channel := range rgb {
+						rgb[channel] += float64(hist.sums[channel][bin]) / count * brightness
+					}
+				}
+			}
+
+			subPixels := float64(f.samples * f.samples)
+			f.Img.SetNRGBA(xPix, yPix, color.NRGBA{
+				uint8(rgb[0] / subPixels),
+				uint8(rgb[1] / subPixels),
+				uint8(rgb[2] / subPixels),
+				255,
+			})
+		}
+	}
+}
@@ -8,16 +8,25 @@ import (
 /* The relationship between fractals and colorFuncs is many to many, so the use
    of a map[string]interface{} kwargs here is justifiable.
 */
-type colorFunc func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64)
-
-var colorSchemes = map[string]colorFunc{
+// ColorFunc colors a point from how many iterations it took, and kwargs from
+// the fractal, which can include "z" (Complex) the final value of z,
+// "iterator" (func(Complex) Complex) the fractal's map, "bailout" (Bailout),
+// "root" and "roots" (int) the root a newton fractal converged to, or -1, and
+// how many there are, and "exponent" (float64) a lyapunov exponent.
+type ColorFunc func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64)
+
+var colorSchemes = map[string]ColorFunc{
 	"simplegrayscale": func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
-		col := float64(255*iterations) / float64(iterationCap)
+		i := float64(iterations) + paletteOffset(kwargs)
+		if i < 0 || i > float64(iterationCap) {
+			i = wrap(i, float64(iterationCap))
+		}
+		col := 255 * i / float64(iterationCap)
 		return col, col, col, 255
 	},
 	"zgrayscale": func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
-		z := kwargs["z"].(complex)
-		col := 255.0 * (math.Mod(z.abs(), 2.0) / 2.0)
+		z := kwargs["z"].(Complex)
+		col := 255.0 * (wrap(z.Abs()+paletteOffset(kwargs), 2.0) / 2.0)
 		return col, col, col, 255
 	},
 
@@ -25,74 +34,89 @@ var colorSchemes = map[string]colorFunc{
 	// !!! Smooth coloring functions only work for some fractals where z is raised to a power of 2 !!!
 	// !!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!
 	"smoothgrayscale": func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
-		z := kwargs["z"].(complex)
-		iterator := kwargs["iterator"].(func(complex) complex)
-
-		z = iterator(iterator(z))
-		iterations += 2
-
-		i := float64(iterations)
-		if iterations < iterationCap {
-			i = i - (math.Log(math.Log(z.abs())) / math.Log(2))
-		}
+		i := smoothIterations(iterations, iterationCap, kwargs)
 
 		if int(math.Floor(i))%2 == 0 {
-			col := 255 * (math.Mod(i, 1))
+			col := 255 * (wrap(i, 1))
 			return col, col, col, 255
 		}
-		col := 255 - (255 * math.Mod(i, 1))
+		col := 255 - (255 * wrap(i, 1))
 		return col, col, col, 255
 
 	},
 	"smoothcolor": func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
-		z := kwargs["z"].(complex)
-		iterator := kwargs["iterator"].(func(complex) complex)
+		i := smoothIterations(iterations, iterationCap, kwargs)
 
-		z = iterator(iterator(z))
-		iterations += 2
-
-		i := float64(iterations)
-		if iterations < iterationCap {
-			i = i - (math.Log(math.Log(z.abs())) / math.Log(2))
-		}
-
-		nu := math.Mod(i, 1)
+		nu := wrap(i, 1)
+		band := int(wrap(math.Floor(i), 3))
 
 		switch {
-		case int(math.Floor(i))%3 == 0:
+		case band == 0:
 			return 255 * nu, 255 * (1 - nu), 255, 255
-		case int(math.Floor(i))%3 == 1:
+		case band == 1:
 			return 255, 255 * nu, 255 * (1 - nu), 255
-		case int(math.Floor(i))%3 == 2:
+		case band == 2:
 			return 255 * (1 - nu), 255, 255 * nu, 255
 		}
 		return 0, 0, 0, 255
 	},
 	"smoothcolor2": func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
-		z := kwargs["z"].(complex)
-		iterator := kwargs["iterator"].(func(complex) complex)
-
-		z = iterator(iterator(z))
-		iterations += 2
-
-		i := float64(iterations)
-		if iterations < iterationCap {
-			i = i - (math.Log(math.Log(z.abs())) / math.Log(2))
-		}
+		i := smoothIterations(iterations, iterationCap, kwargs)
 
-		nu := math.Mod(i, 1)
+		nu := wrap(i, 1)
+		band := int(wrap(math.Floor(i), 3))
 
 		switch {
-		case int(math.Floor(i))%3 == 0:
+		case band == 0:
 			return 255 * (1 - nu), 255 * nu, 0, 255
-		case int(math.Floor(i))%3 == 1:
+		case band == 1:
 			return 0, 255 * (1 - nu), 255 * nu, 255
-		case int(math.Floor(i))%3 == 2:
+		case band == 2:
 			return 255 * nu, 0, 255 * (1 - nu), 255
 		}
 		return 0, 0, 0, 255
 	},
 
+	// 'root' coloring functions give each root of a newton's method fractal its own hue.
+	"rootshaded": func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
+		root, roots := kwargs["root"].(int), kwargs["roots"].(int)
+		if root < 0 {
+			return 0, 0, 0, 255
+		}
+		// Points that take longer to converge are darker
+		value := 1 - math.Sqrt(float64(iterations)/float64(iterationCap))
+		R, G, B = hsv((float64(root)+paletteOffset(kwargs))/float64(roots), 0.8, value)
+		return R, G, B, 255
+	},
+	"rootflat": func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
+		root, roots := kwargs["root"].(int), kwargs["roots"].(int)
+		if root < 0 {
+			return 0, 0, 0, 255
+		}
+		R, G, B = hsv((float64(root)+paletteOffset(kwargs))/float64(roots), 0.8, 1)
+		return R, G, B, 255
+	},
+
+	// Lyapunov exponent coloring functions, stable points are bright and chaotic ones dark.
+	"lyapunov": func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
+		exponent := kwargs["exponent"].(float64)
+		offset := paletteOffset(kwargs)
+		if exponent <= 0 {
+			R, G, B = hsv(0.14+offset, 0.85, math.Tanh(-exponent))
+		} else {
+			R, G, B = hsv(0.62+offset, 0.85, 0.6*math.Tanh(exponent))
+		}
+		return R, G, B, 255
+	},
+	"lyapunovgrayscale": func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
+		exponent := kwargs["exponent"].(float64)
+		col := 0.0
+		if exponent <= 0 {
+			col = 255 * math.Tanh(-exponent)
+		}
+		return col, col, col, 255
+	},
+
 	// 'wacky' coloring functions simply iterate over a set of colors.
 	"wackyrainbow": wacky([]color.RGBA{
 		color.RGBA{84, 110, 98, 255},   // grey-green
@@ -111,10 +135,94 @@ var colorSchemes = map[string]colorFunc{
 }
 
 // returns a color func that cycles through the set of colors passed in
-func wacky(colors []color.RGBA) colorFunc {
+func wacky(colors []color.RGBA) ColorFunc {
 	return func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
-		key := iterations % len(colors)
-		color := colors[key]
+		i := wrap(float64(iterations)+paletteOffset(kwargs), float64(len(colors)))
+		key := int(i)
+		color := colors[key%len(colors)]
+
+		// A fractional palette offset blends into the next color along, so
+		// palettes can be cycled smoothly over the frames of an animation.
+		if nu := i - float64(key); nu > 0 {
+			next := colors[(key+1)%len(colors)]
+			return lerp(float64(color.R), float64(next.R), nu),
+				lerp(float64(color.G), float64(next.G), nu),
+				lerp(float64(color.B), float64(next.B), nu),
+				lerp(float64(color.A), float64(next.A), nu)
+		}
 		return float64(color.R), float64(color.G), float64(color.B), float64(color.A)
 	}
 }
+
+// smoothIterations returns a continuous iteration count for the smooth color
+// funcs, taking in to account the bailout radius and norm and the palette offset
+func smoothIterations(iterations, iterationCap int, kwargs map[string]interface{}) float64 {
+	z := kwargs["z"].(Complex)
+	iterator := kwargs["iterator"].(func(Complex) Complex)
+	bailout, ok := kwargs["bailout"].(Bailout)
+	if !ok {
+		bailout = Bailout{DefaultBailoutRadius, Euclidean}
+	}
+
+	z = iterator(iterator(z))
+	iterations += 2
+
+	i := float64(iterations)
+	size := bailout.Size(z)
+	if iterations < iterationCap && size > 1 && bailout.Radius > 1 {
+		// Scaled so that it's the same as the usual log(log(|z|))/log(2) with a radius of 2
+		i = i - math.Log2(math.Log(size)/math.Log2(bailout.Radius))
+	}
+	return i + paletteOffset(kwargs)
+}
+
+// withPaletteOffset passes a palette offset through to a color func via its kwargs
+func withPaletteOffset(color ColorFunc, offset float64) ColorFunc {
+	return func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
+		kwargs["offset"] = offset
+		return color(iterations, iterationCap, kwargs)
+	}
+}
+
+// paletteOffset returns the palette offset in kwargs, or 0 if there isn't one
+func paletteOffset(kwargs map[string]interface{}) float64 {
+	offset, _ := kwargs["offset"].(float64)
+	return offset
+}
+
+// wrap is math.Mod, but always returns a value in [0, n)
+func wrap(x, n float64) float64 {
+	x = math.Mod(x, n)
+	if x < 0 {
+		x += n
+	}
+	return x
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// hsv converts a hue (wrapping around every 1), saturation and value into 0-255 RGB components
+func hsv(hue, saturation, value float64) (R, G, B float64) {
+	hue = wrap(hue, 1) * 6
+	chroma := value * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue, 2)-1))
+	m := value - chroma
+
+	switch int(hue) {
+	case 0:
+		R, G, B = chroma, x, 0
+	case 1:
+		R, G, B = x, chroma, 0
+	case 2:
+		R, G, B = 0, chroma, x
+	case 3:
+		R, G, B = 0, x, chroma
+	case 4:
+		R, G, B = x, 0, chroma
+	default:
+		R, G, B = chroma, 0, x
+	}
+	return 255 * (R + m), 255 * (G + m), 255 * (B + m)
+}
@@ -4,72 +4,243 @@ import (
 	"math"
 )
 
-type complex struct {
-	real float64
-	imag float64
+// Complex is a complex number, with the methods fractals need to iterate it
+type Complex struct {
+	Real float64
+	Imag float64
 }
 
-func (c complex) abs() float64 {
-	return math.Sqrt((c.real * c.real) + (c.imag * c.imag))
+// Abs returns |c|
+func (c Complex) Abs() float64 {
+	return math.Sqrt((c.Real * c.Real) + (c.Imag * c.Imag))
 }
 
-func (c complex) add(d complex) (e complex) {
-	e.real = c.real + d.real
-	e.imag = c.imag + d.imag
+// Add returns c + d
+func (c Complex) Add(d Complex) (e Complex) {
+	e.Real = c.Real + d.Real
+	e.Imag = c.Imag + d.Imag
 	return e
 }
 
-func (c complex) sub(d complex) (e complex) {
-	e.real = c.real - d.real
-	e.imag = c.imag - d.imag
+// Sub returns c - d
+func (c Complex) Sub(d Complex) (e Complex) {
+	e.Real = c.Real - d.Real
+	e.Imag = c.Imag - d.Imag
 	return e
 }
 
-func (c complex) div(d complex) (e complex) {
-	e.real = ((c.real * d.real) + (c.imag * d.imag)) / ((d.real * d.real) + (d.imag * d.imag))
-	e.imag = ((c.imag * d.real) - (c.real * d.imag)) / ((d.real * d.real) + (d.imag * d.imag))
+// Div returns c / d
+func (c Complex) Div(d Complex) (e Complex) {
+	e.Real = ((c.Real * d.Real) + (c.Imag * d.Imag)) / ((d.Real * d.Real) + (d.Imag * d.Imag))
+	e.Imag = ((c.Imag * d.Real) - (c.Real * d.Imag)) / ((d.Real * d.Real) + (d.Imag * d.Imag))
 	return e
 }
 
-func (c complex) mul(d complex) (e complex) {
-	e.real = (c.real * d.real) - (c.imag * d.imag)
-	e.imag = (c.real * d.imag) + (c.imag * d.real)
+// Mul returns c * d
+func (c Complex) Mul(d Complex) (e Complex) {
+	e.Real = (c.Real * d.Real) - (c.Imag * d.Imag)
+	e.Imag = (c.Real * d.Imag) + (c.Imag * d.Real)
 	return e
 }
 
-//Lazy/shorthand helper. This is faster than pow(2).
-func (c complex) sq() complex {
-	return c.mul(c)
+// Sq returns c squared. This lazy/shorthand helper is faster than Pow(2).
+func (c Complex) Sq() Complex {
+	return c.Mul(c)
 }
 
-func (c complex) pow(n float64) (e complex) {
-	rN := math.Pow(c.real*c.real+c.imag*c.imag, n/2)
-	nTheta := n * math.Atan2(c.imag, c.real)
-	e.real = rN * math.Cos(nTheta)
-	e.imag = rN * math.Sin(nTheta)
+// Pow returns c raised to a real power
+func (c Complex) Pow(n float64) (e Complex) {
+	rN := math.Pow(c.Real*c.Real+c.Imag*c.Imag, n/2)
+	nTheta := n * math.Atan2(c.Imag, c.Real)
+	e.Real = rN * math.Cos(nTheta)
+	e.Imag = rN * math.Sin(nTheta)
 	return e
 }
 
-func (c complex) sin() (e complex) {
-	e.real = math.Sin(c.real) * math.Cosh(c.imag)
-	e.imag = math.Cos(c.real) * math.Sinh(c.imag)
+// Sin returns sin(c)
+func (c Complex) Sin() (e Complex) {
+	sin, cos := math.Sincos(c.Real)
+	sinh, cosh := sinhcosh(c.Imag)
+	e.Real = sin * cosh
+	e.Imag = cos * sinh
 	return e
 }
 
-func (c complex) cos() (e complex) {
-	e.real = math.Cos(c.real) * math.Cosh(c.imag)
-	e.imag = math.Sin(c.real) * math.Sinh(c.imag)
+// Cos returns cos(c)
+func (c Complex) Cos() (e Complex) {
+	sin, cos := math.Sincos(c.Real)
+	sinh, cosh := sinhcosh(c.Imag)
+	e.Real = cos * cosh
+	e.Imag = -sin * sinh
 	return e
 }
 
-func (c complex) exp() (e complex) {
-	e.real = math.Exp(c.real) * math.Cos(c.imag)
-	e.imag = math.Exp(c.real) * math.Sin(c.imag)
+// Exp returns e^c
+func (c Complex) Exp() (e Complex) {
+	r := math.Exp(c.Real)
+	sin, cos := math.Sincos(c.Imag)
+	e.Real = r * cos
+	e.Imag = r * sin
 	return e
 }
 
-func (c complex) conj() (e complex) {
-	e.real = c.real
-	e.imag = -c.imag
+// Conj returns the conjugate of c
+func (c Complex) Conj() (e Complex) {
+	e.Real = c.Real
+	e.Imag = -c.Imag
 	return e
 }
+
+// FromComplex128 converts Go's built in complex type
+func FromComplex128(z complex128) Complex {
+	return Complex{real(z), imag(z)}
+}
+
+// Complex128 converts c to Go's built in complex type
+func (c Complex) Complex128() complex128 {
+	return complex(c.Real, c.Imag)
+}
+
+// Recip returns 1 / c
+func (c Complex) Recip() (e Complex) {
+	d := c.Real*c.Real + c.Imag*c.Imag
+	e.Real = c.Real / d
+	e.Imag = -c.Imag / d
+	return e
+}
+
+// PowInt returns c raised to a whole number power, by repeated squaring,
+// which is faster and more accurate than Pow for small powers
+func (c Complex) PowInt(n int) Complex {
+	if n < 0 {
+		return c.PowInt(-n).Recip()
+	}
+	e := Complex{1, 0}
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			e = e.Mul(c)
+		}
+		c = c.Sq()
+	}
+	return e
+}
+
+// maxPowInt is the largest whole number power powerFunc uses PowInt for
+const maxPowInt = 1 << 20
+
+// powerFunc returns a func raising z to the power n, which uses Sq or PowInt
+// in lieu of Pow for whole number powers, as they're much faster
+func powerFunc(n float64) func(Complex) Complex {
+	switch {
+	case n == 2:
+		return Complex.Sq
+	case n == math.Trunc(n) && math.Abs(n) <= maxPowInt:
+		times := int(n)
+		return func(z Complex) Complex {
+			return z.PowInt(times)
+		}
+	}
+	return func(z Complex) Complex {
+		return z.Pow(n)
+	}
+}
+
+// PowC returns c raised to a complex power, e^(n log(c)). 0 to any power is 0, apart from 0^0 which is 1.
+func (c Complex) PowC(n Complex) Complex {
+	if c.Real == 0 && c.Imag == 0 {
+		if n.Real == 0 && n.Imag == 0 {
+			return Complex{1, 0}
+		}
+		return Complex{0, 0}
+	}
+	return n.Mul(c.Log()).Exp()
+}
+
+// Log returns the principal natural logarithm of c
+func (c Complex) Log() (e Complex) {
+	e.Real = math.Log(math.Hypot(c.Real, c.Imag))
+	e.Imag = math.Atan2(c.Imag, c.Real)
+	return e
+}
+
+// Sqrt returns the principal square root of c
+func (c Complex) Sqrt() (e Complex) {
+	if c.Real == 0 && c.Imag == 0 {
+		return e
+	}
+	t := math.Sqrt((math.Hypot(c.Real, c.Imag) + math.Abs(c.Real)) / 2)
+	if c.Real >= 0 {
+		e.Real = t
+		e.Imag = c.Imag / (2 * t)
+	} else {
+		e.Real = math.Abs(c.Imag) / (2 * t)
+		e.Imag = math.Copysign(t, c.Imag)
+	}
+	return e
+}
+
+// Tan returns tan(c)
+func (c Complex) Tan() (e Complex) {
+	d := math.Cos(2*c.Real) + math.Cosh(2*c.Imag)
+	e.Real = math.Sin(2*c.Real) / d
+	e.Imag = math.Sinh(2*c.Imag) / d
+	return e
+}
+
+// Sinh returns sinh(c)
+func (c Complex) Sinh() (e Complex) {
+	sin, cos := math.Sincos(c.Imag)
+	sinh, cosh := sinhcosh(c.Real)
+	e.Real = sinh * cos
+	e.Imag = cosh * sin
+	return e
+}
+
+// Cosh returns cosh(c)
+func (c Complex) Cosh() (e Complex) {
+	sin, cos := math.Sincos(c.Imag)
+	sinh, cosh := sinhcosh(c.Real)
+	e.Real = cosh * cos
+	e.Imag = sinh * sin
+	return e
+}
+
+// Tanh returns tanh(c)
+func (c Complex) Tanh() (e Complex) {
+	d := math.Cosh(2*c.Real) + math.Cos(2*c.Imag)
+	e.Real = math.Sinh(2*c.Real) / d
+	e.Imag = math.Sin(2*c.Imag) / d
+	return e
+}
+
+// Asin returns the principal inverse sine of c, -i log(ic + sqrt(1 - c^2))
+func (c Complex) Asin() Complex {
+	i := Complex{0, 1}
+	e := i.Mul(c).Add(Complex{1, 0}.Sub(c.Sq()).Sqrt()).Log()
+	return Complex{e.Imag, -e.Real}
+}
+
+// Acos returns the principal inverse cosine of c, pi/2 - asin(c)
+func (c Complex) Acos() Complex {
+	return Complex{math.Pi / 2, 0}.Sub(c.Asin())
+}
+
+// Atan returns the principal inverse tangent of c
+func (c Complex) Atan() (e Complex) {
+	x2 := c.Real * c.Real
+	e.Real = 0.5 * math.Atan2(2*c.Real, 1-x2-c.Imag*c.Imag)
+	e.Imag = 0.25 * math.Log((x2+(c.Imag+1)*(c.Imag+1))/(x2+(c.Imag-1)*(c.Imag-1)))
+	return e
+}
+
+// sinhcosh returns sinh(x) and cosh(x), sharing one call to exp unless x is
+// small enough for that to lose precision
+func sinhcosh(x float64) (sinh, cosh float64) {
+	if math.Abs(x) <= 0.5 {
+		return math.Sinh(x), math.Cosh(x)
+	}
+	e := math.Exp(x)
+	ei := 0.5 / e
+	e *= 0.5
+	return e - ei, e + ei
+}
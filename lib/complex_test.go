@@ -0,0 +1,192 @@
+package lib
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// complexGrid are the points functions are checked at. None of them lie on an
+// axis, so none are on a branch cut, where conventions could differ.
+var complexGrid = func() []complex128 {
+	parts := []float64{-2.1, -0.7, -0.2, 0.3, 0.9, 1.7}
+	grid := []complex128{}
+	for _, re := range parts {
+		for _, im := range parts {
+			grid = append(grid, complex(re, im))
+		}
+	}
+	return grid
+}()
+
+// complexFuncs are each of Complex's functions of one number, with the
+// complex128 expression it should match
+var complexFuncs = []struct {
+	name string
+	fn   func(Complex) Complex
+	want func(complex128) complex128
+}{
+	{"Sq", Complex.Sq, func(z complex128) complex128 { return z * z }},
+	{"Recip", Complex.Recip, func(z complex128) complex128 { return 1 / z }},
+	{"Conj", Complex.Conj, cmplx.Conj},
+	{"Exp", Complex.Exp, cmplx.Exp},
+	{"Log", Complex.Log, cmplx.Log},
+	{"Sqrt", Complex.Sqrt, cmplx.Sqrt},
+	{"Sin", Complex.Sin, cmplx.Sin},
+	{"Cos", Complex.Cos, cmplx.Cos},
+	{"Tan", Complex.Tan, cmplx.Tan},
+	{"Sinh", Complex.Sinh, cmplx.Sinh},
+	{"Cosh", Complex.Cosh, cmplx.Cosh},
+	{"Tanh", Complex.Tanh, cmplx.Tanh},
+	{"Asin", Complex.Asin, cmplx.Asin},
+	{"Acos", Complex.Acos, cmplx.Acos},
+	{"Atan", Complex.Atan, cmplx.Atan},
+	{"Pow 2.5", func(c Complex) Complex { return c.Pow(2.5) }, func(z complex128) complex128 { return cmplx.Pow(z, 2.5) }},
+	{"PowInt 5", func(c Complex) Complex { return c.PowInt(5) }, func(z complex128) complex128 { return cmplx.Pow(z, 5) }},
+	{"PowInt -3", func(c Complex) Complex { return c.PowInt(-3) }, func(z complex128) complex128 { return cmplx.Pow(z, -3) }},
+	{"PowC", func(c Complex) Complex { return c.PowC(Complex{1.5, -0.5}) }, func(z complex128) complex128 { return cmplx.Pow(z, 1.5-0.5i) }},
+}
+
+// closeTo checks two complex numbers are within a relative tolerance
+func closeTo(got, want complex128) bool {
+	return cmplx.Abs(got-want) <= 1e-9*math.Max(1, cmplx.Abs(want))
+}
+
+func TestComplexFuncs(t *testing.T) {
+	for _, test := range complexFuncs {
+		for _, z := range complexGrid {
+			got, want := test.fn(FromComplex128(z)).Complex128(), test.want(z)
+			if !closeTo(got, want) {
+				t.Errorf("%s(%v) = %v, want %v", test.name, z, got, want)
+			}
+		}
+	}
+}
+
+func TestComplexArithmetic(t *testing.T) {
+	for _, y := range complexGrid {
+		for _, z := range complexGrid {
+			c, d := FromComplex128(y), FromComplex128(z)
+			for _, test := range []struct {
+				name      string
+				got, want complex128
+			}{
+				{"Add", c.Add(d).Complex128(), y + z},
+				{"Sub", c.Sub(d).Complex128(), y - z},
+				{"Mul", c.Mul(d).Complex128(), y * z},
+				{"Div", c.Div(d).Complex128(), y / z},
+			} {
+				if !closeTo(test.got, test.want) {
+					t.Errorf("%s(%v, %v) = %v, want %v", test.name, y, z, test.got, test.want)
+				}
+			}
+		}
+		if got, want := FromComplex128(y).Abs(), cmplx.Abs(y); math.Abs(got-want) > 1e-12 {
+			t.Errorf("Abs(%v) = %v, want %v", y, got, want)
+		}
+	}
+}
+
+func TestPowCZero(t *testing.T) {
+	if got := (Complex{}).PowC(Complex{}); got != (Complex{1, 0}) {
+		t.Errorf("0^0 = %v, want 1", got)
+	}
+	if got := (Complex{}).PowC(Complex{2, 1}); got != (Complex{}) {
+		t.Errorf("0^(2+i) = %v, want 0", got)
+	}
+}
+
+// complexSink keeps benchmarked results from being optimised away
+var complexSink complex128
+
+// BenchmarkComplexFuncs benchmarks each function against complex128, in pairs
+// of sub-benchmarks named for the function
+func BenchmarkComplexFuncs(b *testing.B) {
+	z := 0.3 + 0.9i
+	for _, test := range complexFuncs {
+		b.Run(test.name+"/Complex", func(b *testing.B) {
+			c, e := FromComplex128(z), Complex{}
+			for i := 0; i < b.N; i++ {
+				e = test.fn(c)
+			}
+			complexSink = e.Complex128()
+		})
+		b.Run(test.name+"/complex128", func(b *testing.B) {
+			var y complex128
+			for i := 0; i < b.N; i++ {
+				y = test.want(z)
+			}
+			complexSink = y
+		})
+	}
+}
+
+func BenchmarkComplexMul(b *testing.B) {
+	c, d := Complex{0.3, 0.9}, Complex{0.6, 0.8}
+	for i := 0; i < b.N; i++ {
+		c = c.Mul(d)
+	}
+	complexSink = c.Complex128()
+}
+
+func BenchmarkComplex128Mul(b *testing.B) {
+	y, z := 0.3+0.9i, 0.6+0.8i
+	for i := 0; i < b.N; i++ {
+		y = y * z
+	}
+	complexSink = y
+}
+
+func TestPowerFunc(t *testing.T) {
+	powers := []float64{2, 3, 4, 5, 6, 7, 8, -1, -2, -5, 0, 2.5, -1.5, 3.7}
+	for _, n := range powers {
+		pow := powerFunc(n)
+		for _, z := range complexGrid {
+			c := FromComplex128(z)
+			if got, want := pow(c).Complex128(), c.Pow(n).Complex128(); !closeTo(got, want) {
+				t.Errorf("powerFunc(%v)(%v) = %v, want %v as from Pow", n, z, got, want)
+			}
+		}
+	}
+}
+
+// multibrotIterations iterates the multibrot for a grid of points with the
+// power func, as its point func does, returning the total iterations
+func multibrotIterations(pow func(Complex) Complex) int {
+	total := 0
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			c := Complex{-1.5 + 3*float64(x)/32, -1.5 + 3*float64(y)/32}
+			z := Complex{}
+			iterations := 0
+			for ; z.Real*z.Real+z.Imag*z.Imag <= 4 && iterations < 64; iterations++ {
+				z = pow(z).Add(c)
+			}
+			total += iterations
+		}
+	}
+	return total
+}
+
+// BenchmarkMultibrot compares iterating the multibrot with powerFunc's whole
+// number powers against the trig path of Pow, for each power
+func BenchmarkMultibrot(b *testing.B) {
+	for n := 3; n <= 8; n++ {
+		power := float64(n)
+		b.Run(fmt.Sprintf("power=%d/PowInt", n), func(b *testing.B) {
+			pow := powerFunc(power)
+			for i := 0; i < b.N; i++ {
+				multibrotIterations(pow)
+			}
+		})
+		b.Run(fmt.Sprintf("power=%d/Pow", n), func(b *testing.B) {
+			pow := func(z Complex) Complex {
+				return z.Pow(power)
+			}
+			for i := 0; i < b.N; i++ {
+				multibrotIterations(pow)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package lib
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// The buddhabrot is a map of where the orbits of points go rather than how
+// quickly they escape, so in lieu of working out a color for each pixel a
+// DensityGenerator scatters the orbits of randomly sampled points across a
+// histogram, and tone maps that into an image. Each color channel has its own
+// iteration limit, so different limits in each make a nebulabrot.
+
+// Errors for density renders
+var (
+	ErrNoMap          = errors.New("fractal has no map for density rendering")
+	ErrInvalidToneMap = errors.New("invalid tone map name")
+)
+
+// densityChunk is how many samples a routine takes at a time. Each chunk has
+// its own random seed, so the result doesn't depend upon the number of routines.
+const densityChunk = 1 << 14
+
+// Orbiter iterates the points sampled by a density generator
+type Orbiter struct {
+	fn      func(z, c Complex) Complex
+	bailout Bailout
+}
+
+// GetOrbiter checks for a valid fractal name and parameters, and returns an
+// Orbiter for the fractal's map if it has one
+func GetOrbiter(fractalName string, constants []float64, opts Options) (Orbiter, error) {
+	frac, err := GetFractal(fractalName)
+	if err != nil {
+		return Orbiter{}, err
+	}
+	if frac.Map == nil {
+		return Orbiter{}, ErrNoMap
+	}
+	params, err := frac.params(constants, nil, opts.Params)
+	if err != nil {
+		return Orbiter{}, err
+	}
+	bailout, err := frac.bailout(opts)
+	if err != nil {
+		return Orbiter{}, err
+	}
+	return Orbiter{frac.Map(params), bailout}, nil
+}
+
+// ToneMap maps a histogram count to a brightness in [0, 1], given the channel's largest count
+type ToneMap func(count, max float64) float64
+
+// ToneMaps is a map of the available tone maps by name
+var ToneMaps = map[string]ToneMap{
+	"linear": func(count, max float64) float64 {
+		return count / max
+	},
+	"sqrt": func(count, max float64) float64 {
+		return math.Sqrt(count / max)
+	},
+	"log": func(count, max float64) float64 {
+		return math.Log1p(count) / math.Log1p(max)
+	},
+}
+
+// GetToneMap returns a tone map if the name is valid
+func GetToneMap(name string) (ToneMap, error) {
+	toneMap, validToneMap := ToneMaps[strings.ToLower(name)]
+	if !validToneMap {
+		return nil, ErrInvalidToneMap
+	}
+	return toneMap, nil
+}
+
+// DensityGenerator is our runner for density renders!
+type DensityGenerator struct {
+	Img      *image.NRGBA
+	xPos     float64
+	yPos     float64
+	zoom     float64
+	scaler   float64
+	width    int
+	height   int
+	routines int
+	samples  int
+	limits   [3]int
+	minimum  int
+	orbiter  Orbiter
+	anti     bool
+	seed     int64
+	toneMap  ToneMap
+	exposure float64
+}
+
+// NewDensityGenerator returns a density generator, which samples the given
+// number of points, with iteration limits for the red, green and blue channels.
+func NewDensityGenerator(width, height, routines, samples int, limits [3]int, xPos, yPos, zoom float64, orbiter Orbiter) DensityGenerator {
+	return DensityGenerator{
+		image.NewNRGBA(image.Rect(0, 0, width, height)),
+		xPos,
+		yPos,
+		zoom,
+		scaler(width, height),
+		width,
+		height,
+		routines,
+		samples,
+		limits,
+		0,
+		orbiter,
+		false,
+		0,
+		ToneMaps["sqrt"],
+		1,
+	}
+}
+
+// SetAnti switches to the anti-buddhabrot, which plots the orbits of the
+// points that don't escape in lieu of those that do.
+func (f *DensityGenerator) SetAnti(anti bool) {
+	f.anti = anti
+}
+
+// SetMinIterations leaves out the orbits of points that escape in fewer
+// iterations than this, which otherwise give a noisy background.
+func (f *DensityGenerator) SetMinIterations(minimum int) {
+	f.minimum = minimum
+}
+
+// SetSeed sets the seed the points are sampled with
+func (f *DensityGenerator) SetSeed(seed int64) {
+	f.seed = seed
+}
+
+// SetToneMap sets how the histogram is mapped to brightness. The exposure
+// scales the brightness before it's clipped to white.
+func (f *DensityGenerator) SetToneMap(toneMap ToneMap, exposure float64) {
+	f.toneMap = toneMap
+	f.exposure = exposure
+}
+
+// Generate spins out our workers, and tone maps what they find.
+func (f DensityGenerator) Generate() {
+	var histograms [3][]uint32
+	for channel := range histograms {
+		histograms[channel] = make([]uint32, f.width*f.height)
+	}
+
+	chunks := (f.samples + densityChunk - 1) / densityChunk
+	next := int64(-1)
+
+	var wg sync.WaitGroup
+	wg.Add(f.routines)
+	for rno := 0; rno < f.routines; rno++ {
+		go func() {
+			defer wg.Done()
+			for chunk := int(atomic.AddInt64(&next, 1)); chunk < chunks; chunk = int(atomic.AddInt64(&next, 1)) {
+				samples := densityChunk
+				if chunk == chunks-1 {
+					samples = f.samples - chunk*densityChunk
+				}
+				f.scatter(histograms, rand.New(rand.NewSource(f.seed+int64(chunk))), samples)
+			}
+		}()
+	}
+	wg.Wait()
+
+	f.toneMapInto(histograms)
+}
+
+// scatter samples points and adds their orbits to the histograms
+func (f DensityGenerator) scatter(histograms [3][]uint32, rng *rand.Rand, samples int) {
+	maxLimit := 0
+	for _, limit := range f.limits {
+		if limit > maxLimit {
+			maxLimit = limit
+		}
+	}
+	orbit := make([]Complex, 0, maxLimit)
+
+	// The sets all lie within a radius of 2
+	radius := math.Min(f.orbiter.bailout.Radius, DefaultBailoutRadius)
+	pixelSize := (2 / f.scaler) / f.zoom
+
+	for sample := 0; sample < samples; sample++ {
+		c := Complex{(2*rng.Float64() - 1) * radius, (2*rng.Float64() - 1) * radius}
+
+		orbit = orbit[:0]
+		z := Complex{0, 0}
+		escaped := false
+		for len(orbit) < maxLimit {
+			z = f.orbiter.fn(z, c)
+			orbit = append(orbit, z)
+			if f.orbiter.bailout.Escaped(z) {
+				escaped = true
+				break
+			}
+		}
+
+		for channel, limit := range f.limits {
+			var points []Complex
+			switch {
+			case !f.anti && escaped && len(orbit) <= limit && len(orbit) >= f.minimum:
+				points = orbit
+			case f.anti && !(escaped && len(orbit) <= limit):
+				points = orbit[:limit]
+			default:
+				continue
+			}
+
+			for _, point := range points {
+				xPix := int(math.Floor((point.Real-f.xPos)/pixelSize + float64(f.width)/2 + 0.5))
+				yPix := int(math.Floor((point.Imag-f.yPos)/pixelSize + float64(f.height)/2 + 0.5))
+				if xPix < 0 || xPix >= f.width || yPix < 0 || yPix >= f.height {
+					continue
+				}
+				atomic.AddUint32(&histograms[channel][yPix*f.width+xPix], 1)
+			}
+		}
+	}
+}
+
+// toneMapInto maps each channel's histogram into the image
+func (f DensityGenerator) toneMapInto(histograms [3][]uint32) {
+	var max [3]float64
+	for channel, histogram := range histograms {
+		for _, count := range histogram {
+			max[channel] = math.Max(max[channel], float64(count))
+		}
+	}
+
+	for key := 0; key < f.width*f.height; key++ {
+		var rgb [3]uint8
+		for channel, histogram := range histograms {
+			if max[channel] == 0 {
+				continue
+			}
+			value := f.toneMap(float64(histogram[key]), max[channel]) * f.exposure
+			rgb[channel] = uint8(255 * math.Min(value, 1))
+		}
+		f.Img.SetNRGBA(key%f.width, key/f.width, color.NRGBA{rgb[0], rgb[1], rgb[2], 255})
+	}
+}
@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sync"
+)
+
+// An exponential map is a strip of log-polar samples around a centre. Each
+// column is an angle, and each row is a radius smaller than the one above it
+// by a constant factor, chosen so that pixels stay roughly square. One tall
+// strip therefore covers every zoom level between two depths, and the frames
+// of a zoom video can be resampled from it far more cheaply than rendering
+// each of them.
+
+// expMapOuterRadius is the radius of row 0 at a zoom factor of 1: the
+// corners of a square view.
+const expMapOuterRadius = math.Sqrt2
+
+// ExpMapHeight returns how many rows an exponential map strip of the given
+// width needs to cover every zoom factor from zoom down to depth, including
+// the centres of frames up to about width/2π pixels from centre to corner.
+func ExpMapHeight(width int, zoom, depth float64) int {
+	rowsPerE := float64(width) / (2 * math.Pi)
+	return int(math.Ceil((math.Log(depth/zoom) + math.Log(math.Max(rowsPerE, 1))) * rowsPerE))
+}
+
+// ExpMapDepth is the inverse of ExpMapHeight: it returns how many times deeper
+// than its starting zoom factor an exponential map strip of the given size goes.
+func ExpMapDepth(width, height int) float64 {
+	rowsPerE := float64(width) / (2 * math.Pi)
+	return math.Exp(float64(height)/rowsPerE) / math.Max(rowsPerE, 1)
+}
+
+// NewExpMapGenerator returns a generator for an exponential map strip around
+// (xPos, yPos) that covers every zoom factor from zoom down to depth, which
+// must be deeper than zoom.
+func NewExpMapGenerator(width, routines, iterationCap, samples int, xPos, yPos, zoom, depth float64, fn PointFunc) (Generator, error) {
+	if !(zoom > 0) || !(depth > zoom) {
+		return Generator{}, fmt.Errorf("%w: an exp map's depth must be more than its zoom factor, which must be more than 0", ErrInvalidView)
+	}
+	gen := NewGenerator(width, ExpMapHeight(width, zoom, depth), routines, iterationCap, samples, xPos, yPos, zoom, fn)
+	gen.expMap = true
+	return gen, nil
+}
+
+func (f Generator) expMapToCoord(xPix, yPix float64) (xCoord, yCoord float64) {
+	theta := 2 * math.Pi * xPix / float64(f.width)
+	radius := (expMapOuterRadius / f.zoom) * math.Exp(-2*math.Pi*yPix/float64(f.width))
+	xOffset, yOffset := radius*math.Cos(theta), radius*math.Sin(theta)
+	xCoord = (xOffset * f.matrix[0]) + (yOffset * f.matrix[1]) + f.xPos
+	yCoord = (xOffset * f.matrix[2]) + (yOffset * f.matrix[3]) + f.yPos
+	return xCoord, yCoord
+}
+
+// UnrollExpMap resamples an exponential map strip into an ordinary frame of
+// the given size, zoomed in by the given factor from the strip's starting zoom.
+func UnrollExpMap(strip *image.NRGBA, width, height, routines int, zoom float64) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	stripWidth := float64(strip.Bounds().Dx())
+	rowsPerE := stripWidth / (2 * math.Pi)
+	halfSize := math.Min(float64(width), float64(height)) / 2
+
+	var wg sync.WaitGroup
+	wg.Add(routines)
+	for routine := 0; routine < routines; routine++ {
+		go func(routine int) {
+			for yPix := routine; yPix < height; yPix += routines {
+				for xPix := 0; xPix < width; xPix++ {
+					// Use the same pixel to offset mapping as Generator.pixToCoord
+					xOffset := float64(xPix) - float64(width)/2
+					yOffset := float64(yPix) - float64(height)/2
+					radius := math.Hypot(xOffset, yOffset) / halfSize / zoom
+
+					theta := math.Atan2(yOffset, xOffset)
+					if theta < 0 {
+						theta += 2 * math.Pi
+					}
+
+					// The very centre pixel is infinitely deep, so take it from the bottom row
+					row := math.Inf(1)
+					if radius > 0 {
+						row = math.Log(expMapOuterRadius/radius) * rowsPerE
+					}
+
+					img.SetNRGBA(xPix, yPix, sampleStrip(strip, theta*rowsPerE, row))
+				}
+			}
+			wg.Done()
+		}(routine)
+	}
+	wg.Wait()
+
+	return img
+}
+
+// sampleStrip bilinearly interpolates a strip, wrapping around horizontally
+// and clamping vertically.
+func sampleStrip(strip *image.NRGBA, x, y float64) color.NRGBA {
+	bounds := strip.Bounds()
+	y = math.Max(0, math.Min(y, float64(bounds.Dy()-1)))
+
+	x0, y0 := math.Floor(x), math.Floor(y)
+	xt, yt := x-x0, y-y0
+
+	col := func(x, y int) color.NRGBA {
+		x = int(wrap(float64(x), float64(bounds.Dx())))
+		if y >= bounds.Dy() {
+			y = bounds.Dy() - 1
+		}
+		return strip.NRGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+	}
+	a, b := col(int(x0), int(y0)), col(int(x0)+1, int(y0))
+	c, d := col(int(x0), int(y0)+1), col(int(x0)+1, int(y0)+1)
+
+	blend := func(a, b, c, d uint8) uint8 {
+		return uint8(math.Round(lerp(
+			lerp(float64(a), float64(b), xt),
+			lerp(float64(c), float64(d), xt),
+			yt)))
+	}
+	return color.NRGBA{
+		blend(a.R, b.R, c.R, d.R),
+		blend(a.G, b.G, c.G, d.G),
+		blend(a.B, b.B, c.B, d.B),
+		blend(a.A, b.A, c.A, d.A),
+	}
+}
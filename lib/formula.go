@@ -0,0 +1,752 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ErrInvalidFormula is wrapped by every error from parsing or compiling a formula
+var ErrInvalidFormula = errors.New("invalid formula")
+
+// Formulas let fractals be defined at runtime rather than by adding to the
+// Fractals map, e.g.
+//
+//	z^3 + c*sin(z)
+//
+// A formula is a list of statements separated by semicolons. The last one is
+// the expression for the next value of z. The ones before it are assignments,
+// run once per point before iterating, which can set up user constants or
+// change the starting values of the variables:
+//
+//	z      the value being iterated, starting at 0
+//	c      starting at the point being rendered
+//	pixel  the point being rendered
+//	k1     the first of the -c constants, k2 the second, and so on
+//
+// and any parameters named with -p, e.g. -p a=0.3+0.1i defines a.
+//
+// So a Julia set is
+//
+//	c = -0.2 + 0.65i; z = pixel; z^2 + c
+//
+// The bailout condition is a second formula that is true once a point has
+// escaped, e.g. re(z) > 4. Without one, points escape once z passes the
+// bailout radius. There are no booleans: comparisons give 1 for true and 0
+// for false, <, <=, > and >= compare real parts, == and != compare whole
+// values, and anything with a non-zero real part counts as true.
+//
+// Formulas are compiled into a tree of closures, with any parts that don't
+// depend upon the point being rendered worked out ahead of time.
+
+// formulaExpr evaluates part of a formula given the values of its variables
+type formulaExpr func(vars []Complex) Complex
+
+// formulaNode is a compiled part of a formula. If it's constant, value holds
+// its value and it doesn't need evaluating for every point.
+type formulaNode struct {
+	eval     formulaExpr
+	constant bool
+	value    Complex
+}
+
+var formulaUnaryFuncs = map[string]func(Complex) Complex{
+	"sin":   Complex.Sin,
+	"cos":   Complex.Cos,
+	"exp":   Complex.Exp,
+	"conj":  Complex.Conj,
+	"sq":    Complex.Sq,
+	"log":   Complex.Log,
+	"sqrt":  Complex.Sqrt,
+	"tan":   Complex.Tan,
+	"sinh":  Complex.Sinh,
+	"cosh":  Complex.Cosh,
+	"tanh":  Complex.Tanh,
+	"asin":  Complex.Asin,
+	"acos":  Complex.Acos,
+	"atan":  Complex.Atan,
+	"recip": Complex.Recip,
+	"abs": func(z Complex) Complex {
+		return Complex{z.Abs(), 0}
+	},
+	"re": func(z Complex) Complex {
+		return Complex{z.Real, 0}
+	},
+	"im": func(z Complex) Complex {
+		return Complex{z.Imag, 0}
+	},
+	// The absolute values of the components, as in the burning ship fractal
+	"absc": func(z Complex) Complex {
+		return Complex{math.Abs(z.Real), math.Abs(z.Imag)}
+	},
+}
+
+var formulaBinaryFuncs = map[string]func(Complex, Complex) Complex{
+	"pow": func(z, n Complex) Complex {
+		if n.Imag != 0 {
+			return z.PowC(n)
+		}
+		return z.Pow(n.Real)
+	},
+}
+
+// The variables every formula has, and their indices in its vars
+const (
+	formulaZ = iota
+	formulaC
+	formulaPixel
+	formulaBuiltins
+)
+
+// Formula is a compiled user defined fractal
+type Formula struct {
+	vars    []Complex
+	init    []formulaStatement
+	iterate formulaExpr
+	bailout formulaExpr // nil to use the bailout radius
+}
+
+type formulaStatement struct {
+	variable int
+	eval     formulaExpr
+}
+
+// CompileFormula compiles a formula and its bailout condition, which may be
+// empty. The float and complex parameters are available to both by name.
+func CompileFormula(formula, bailout string, params Params) (*Formula, error) {
+	c := &formulaCompiler{
+		names:     map[string]int{"z": formulaZ, "c": formulaC, "pixel": formulaPixel},
+		constants: make([]bool, formulaBuiltins),
+		vars:      make([]Complex, formulaBuiltins),
+	}
+	for name, val := range params {
+		if _, builtin := c.names[name]; builtin {
+			return nil, fmt.Errorf("%w: %s is a builtin variable, not a parameter", ErrInvalidFormula, name)
+		}
+		switch val := val.(type) {
+		case float64:
+			c.define(name, formulaNode{constant: true, value: Complex{val, 0}})
+		case Complex:
+			c.define(name, formulaNode{constant: true, value: val})
+		}
+	}
+
+	f := &Formula{}
+
+	statements, err := parseFormula(formula)
+	if err != nil {
+		return nil, err
+	}
+	for key, statement := range statements {
+		last := key == len(statements)-1
+		if last != (statement.assign == "") {
+			return nil, fmt.Errorf("%w: every statement but the last must be an assignment, and the last must be the next value of z", ErrInvalidFormula)
+		}
+
+		node, err := c.compile(statement.expr)
+		if err != nil {
+			return nil, err
+		}
+
+		if last {
+			f.iterate = node.evaluator()
+			break
+		}
+
+		/* Assignments that don't depend upon the point are done once now,
+		   apart from to z, which changes with every iteration.
+		*/
+		if node.constant && statement.assign != "z" {
+			c.define(statement.assign, node)
+			continue
+		}
+		index := c.define(statement.assign, formulaNode{})
+		f.init = append(f.init, formulaStatement{index, node.evaluator()})
+	}
+
+	if strings.TrimSpace(bailout) == "" {
+		f.vars = c.vars
+		return f, nil
+	}
+
+	// The bailout can use anything the formula defines
+	bailoutStatements, err := parseFormula(bailout)
+	if err != nil {
+		return nil, err
+	}
+	if len(bailoutStatements) != 1 || bailoutStatements[0].assign != "" {
+		return nil, fmt.Errorf("%w: a bailout condition must be a single expression", ErrInvalidFormula)
+	}
+	node, err := c.compile(bailoutStatements[0].expr)
+	if err != nil {
+		return nil, err
+	}
+	f.bailout = node.evaluator()
+
+	f.vars = c.vars
+	return f, nil
+}
+
+// fractalFunc lets a formula be used as the Fn of a Fractal
+func (f *Formula) fractalFunc(color ColorFunc, params Params, bailout Bailout) PointFunc {
+	escaped := func(vars []Complex) bool {
+		return bailout.Escaped(vars[formulaZ])
+	}
+	if f.bailout != nil {
+		escaped = func(vars []Complex) bool {
+			return f.bailout(vars).Real != 0
+		}
+	}
+
+	// The point func is shared by the routines, so each point borrows its vars
+	pool := sync.Pool{New: func() interface{} {
+		vars := make([]Complex, len(f.vars))
+		return &vars
+	}}
+
+	return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+		borrowed := pool.Get().(*[]Complex)
+		vars := *borrowed
+		copy(vars, f.vars)
+		vars[formulaPixel] = Complex{xCoord, yCoord}
+		vars[formulaC] = vars[formulaPixel]
+
+		for _, statement := range f.init {
+			vars[statement.variable] = statement.eval(vars)
+		}
+
+		iterate := func(z Complex) Complex {
+			vars[formulaZ] = z
+			return f.iterate(vars)
+		}
+
+		iterations := 0
+		for iterations = 0; !escaped(vars) && iterations < iterationCap; iterations++ {
+			vars[formulaZ] = f.iterate(vars)
+		}
+
+		R, G, B, A = color(
+			iterations,
+			iterationCap,
+			map[string]interface{}{
+				"z":        vars[formulaZ],
+				"iterator": iterate,
+				"bailout":  bailout,
+			},
+		)
+		pool.Put(borrowed)
+		return R, G, B, A
+	}
+}
+
+// formulaColorSchemes are the color schemes that work with any formula
+var formulaColorSchemes = []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"}
+
+// GetFormulaPointFunc compiles a formula and bailout condition, checks the
+// color scheme name, and returns a PointFunc if we're good to go
+func GetFormulaPointFunc(formula, bailout, colorName string, constants []float64, opts Options) (PointFunc, error) {
+	frac := &Fractal{
+		Description:        formula,
+		Params:             formulaParams(len(constants), opts.Params),
+		ColorSchemes:       formulaColorSchemes,
+		DefaultColorScheme: "simplegrayscale",
+	}
+	params, err := frac.params(constants, opts.Strings, opts.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := CompileFormula(formula, bailout, params)
+	if err != nil {
+		return nil, err
+	}
+	frac.Fn = f.fractalFunc
+	return frac.pointFunc(colorName, constants, opts)
+}
+
+// formulaParams are the parameters of a formula: k1, k2 and so on for the
+// constants, and a complex for each other named value
+func formulaParams(constants int, named map[string]string) []Param {
+	params := []Param{}
+	for key := 0; key < constants; key++ {
+		params = append(params, Param{Name: fmt.Sprintf("k%d", key+1), Type: ParamFloat, Help: "constant"})
+	}
+	for name := range named {
+		if _, exists := (&Fractal{Params: params}).param(name); !exists {
+			params = append(params, Param{Name: name, Type: ParamComplex, Help: "named constant"})
+		}
+	}
+	return params
+}
+
+func (n formulaNode) evaluator() formulaExpr {
+	if n.constant {
+		value := n.value
+		return func(vars []Complex) Complex {
+			return value
+		}
+	}
+	return n.eval
+}
+
+type formulaCompiler struct {
+	names     map[string]int
+	constants []bool
+	vars      []Complex
+}
+
+// define (re)defines a variable, returning its index in vars
+func (c *formulaCompiler) define(name string, node formulaNode) int {
+	index, exists := c.names[name]
+	if !exists {
+		index = len(c.vars)
+		c.names[name] = index
+		c.vars = append(c.vars, Complex{})
+		c.constants = append(c.constants, false)
+	}
+	c.constants[index] = node.constant
+	if node.constant {
+		c.vars[index] = node.value
+	}
+	return index
+}
+
+func (c *formulaCompiler) compile(e *formulaAST) (formulaNode, error) {
+	switch e.op {
+	case "num":
+		return formulaNode{constant: true, value: e.value}, nil
+
+	case "var":
+		index, exists := c.names[e.name]
+		if !exists {
+			return formulaNode{}, fmt.Errorf("%w: unknown variable %q", ErrInvalidFormula, e.name)
+		}
+		if c.constants[index] {
+			return formulaNode{constant: true, value: c.vars[index]}, nil
+		}
+		return formulaNode{eval: func(vars []Complex) Complex {
+			return vars[index]
+		}}, nil
+
+	case "call":
+		args := make([]formulaNode, len(e.args))
+		for key, arg := range e.args {
+			node, err := c.compile(arg)
+			if err != nil {
+				return formulaNode{}, err
+			}
+			args[key] = node
+		}
+		if fn, exists := formulaUnaryFuncs[e.name]; exists {
+			if len(args) != 1 {
+				return formulaNode{}, fmt.Errorf("%w: %s takes 1 argument", ErrInvalidFormula, e.name)
+			}
+			return unaryNode(args[0], fn), nil
+		}
+		if fn, exists := formulaBinaryFuncs[e.name]; exists {
+			if len(args) != 2 {
+				return formulaNode{}, fmt.Errorf("%w: %s takes 2 arguments", ErrInvalidFormula, e.name)
+			}
+			if e.name == "pow" {
+				return powNode(args[0], args[1]), nil
+			}
+			return binaryNode(args[0], args[1], fn), nil
+		}
+		return formulaNode{}, fmt.Errorf("%w: unknown function %q", ErrInvalidFormula, e.name)
+	}
+
+	args := make([]formulaNode, len(e.args))
+	for key, arg := range e.args {
+		node, err := c.compile(arg)
+		if err != nil {
+			return formulaNode{}, err
+		}
+		args[key] = node
+	}
+
+	switch e.op {
+	case "neg":
+		return unaryNode(args[0], func(z Complex) Complex {
+			return Complex{-z.Real, -z.Imag}
+		}), nil
+	case "!":
+		return unaryNode(args[0], func(z Complex) Complex {
+			return truth(z.Real == 0)
+		}), nil
+	case "+":
+		return binaryNode(args[0], args[1], Complex.Add), nil
+	case "-":
+		return binaryNode(args[0], args[1], Complex.Sub), nil
+	case "*":
+		return binaryNode(args[0], args[1], Complex.Mul), nil
+	case "/":
+		return binaryNode(args[0], args[1], Complex.Div), nil
+	case "^":
+		return powNode(args[0], args[1]), nil
+	case "<":
+		return binaryNode(args[0], args[1], func(a, b Complex) Complex { return truth(a.Real < b.Real) }), nil
+	case "<=":
+		return binaryNode(args[0], args[1], func(a, b Complex) Complex { return truth(a.Real <= b.Real) }), nil
+	case ">":
+		return binaryNode(args[0], args[1], func(a, b Complex) Complex { return truth(a.Real > b.Real) }), nil
+	case ">=":
+		return binaryNode(args[0], args[1], func(a, b Complex) Complex { return truth(a.Real >= b.Real) }), nil
+	case "==":
+		return binaryNode(args[0], args[1], func(a, b Complex) Complex { return truth(a == b) }), nil
+	case "!=":
+		return binaryNode(args[0], args[1], func(a, b Complex) Complex { return truth(a != b) }), nil
+	case "&&", "||":
+		and := e.op == "&&"
+		if args[0].constant {
+			// Short circuit ahead of time
+			if (args[0].value.Real != 0) != and {
+				return formulaNode{constant: true, value: truth(!and)}, nil
+			}
+			return unaryNode(args[1], func(z Complex) Complex { return truth(z.Real != 0) }), nil
+		}
+		lhs, rhs := args[0].evaluator(), args[1].evaluator()
+		return formulaNode{eval: func(vars []Complex) Complex {
+			if (lhs(vars).Real != 0) != and {
+				return truth(!and)
+			}
+			return truth(rhs(vars).Real != 0)
+		}}, nil
+	}
+	return formulaNode{}, fmt.Errorf("%w: unknown operator %q", ErrInvalidFormula, e.op)
+}
+
+func unaryNode(arg formulaNode, fn func(Complex) Complex) formulaNode {
+	if arg.constant {
+		return formulaNode{constant: true, value: fn(arg.value)}
+	}
+	eval := arg.eval
+	return formulaNode{eval: func(vars []Complex) Complex {
+		return fn(eval(vars))
+	}}
+}
+
+func binaryNode(lhs, rhs formulaNode, fn func(Complex, Complex) Complex) formulaNode {
+	switch {
+	case lhs.constant && rhs.constant:
+		return formulaNode{constant: true, value: fn(lhs.value, rhs.value)}
+	case lhs.constant:
+		a, b := lhs.value, rhs.eval
+		return formulaNode{eval: func(vars []Complex) Complex {
+			return fn(a, b(vars))
+		}}
+	case rhs.constant:
+		a, b := lhs.eval, rhs.value
+		return formulaNode{eval: func(vars []Complex) Complex {
+			return fn(a(vars), b)
+		}}
+	}
+	a, b := lhs.eval, rhs.eval
+	return formulaNode{eval: func(vars []Complex) Complex {
+		return fn(a(vars), b(vars))
+	}}
+}
+
+// powNode raises to a power, using powerFunc for constant real powers, as its
+// whole number powers are much faster than Complex.Pow.
+func powNode(base, power formulaNode) formulaNode {
+	if power.constant && power.value.Imag == 0 {
+		return unaryNode(base, powerFunc(power.value.Real))
+	}
+	return binaryNode(base, power, formulaBinaryFuncs["pow"])
+}
+
+func truth(b bool) Complex {
+	if b {
+		return Complex{1, 0}
+	}
+	return Complex{0, 0}
+}
+
+// formulaAST is a parsed expression
+type formulaAST struct {
+	op    string
+	name  string
+	value Complex
+	args  []*formulaAST
+}
+
+type formulaStatementAST struct {
+	assign string
+	expr   *formulaAST
+}
+
+// formulaToken is a number, a name or an operator
+type formulaToken struct {
+	kind  string // "num", "name", "op" or "end"
+	text  string
+	value Complex
+	pos   int
+}
+
+func lexFormula(src string) ([]formulaToken, error) {
+	tokens := []formulaToken{}
+	runes := []rune(src)
+	isNameRune := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			// exponents, e.g. 1e-3
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				j := i + 1
+				if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+					j++
+				}
+				if j < len(runes) && unicode.IsDigit(runes[j]) {
+					for i = j; i < len(runes) && unicode.IsDigit(runes[i]); i++ {
+					}
+				}
+			}
+			val, err := strconv.ParseFloat(string(runes[start:i]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad number %q at %d", ErrInvalidFormula, string(runes[start:i]), start)
+			}
+			value := Complex{val, 0}
+			// Imaginary literals, e.g. 0.65i
+			if i < len(runes) && runes[i] == 'i' && (i+1 == len(runes) || !isNameRune(runes[i+1])) {
+				value = Complex{0, val}
+				i++
+			}
+			tokens = append(tokens, formulaToken{"num", string(runes[start:i]), value, start})
+
+		case isNameRune(r):
+			start := i
+			for i < len(runes) && isNameRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, formulaToken{"name", string(runes[start:i]), Complex{}, start})
+
+		default:
+			op := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "<=", ">=", "==", "!=", "&&", "||":
+					op = two
+				}
+			}
+			if len(op) == 1 && !strings.Contains("+-*/^()|,;=<>!", op) {
+				return nil, fmt.Errorf("%w: unexpected %q at %d", ErrInvalidFormula, op, i)
+			}
+			tokens = append(tokens, formulaToken{"op", op, Complex{}, i})
+			i += len([]rune(op))
+		}
+	}
+
+	return append(tokens, formulaToken{"end", "", Complex{}, len(runes)}), nil
+}
+
+func parseFormula(src string) ([]formulaStatementAST, error) {
+	tokens, err := lexFormula(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &formulaParser{tokens: tokens}
+
+	statements := []formulaStatementAST{}
+	for {
+		statement := formulaStatementAST{}
+		if p.peek().kind == "name" && p.tokens[p.pos+1].text == "=" {
+			statement.assign = p.next().text
+			if statement.assign == "i" {
+				return nil, fmt.Errorf("%w: i can't be assigned to", ErrInvalidFormula)
+			}
+			p.next()
+		}
+		statement.expr, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+
+		switch tok := p.next(); {
+		case tok.kind == "end":
+			return statements, nil
+		case tok.text != ";":
+			return nil, p.unexpected(tok)
+		}
+		// Allow a trailing semicolon
+		if p.peek().kind == "end" {
+			return statements, nil
+		}
+	}
+}
+
+// formulaParser is a recursive descent parser, with one function per level of precedence
+type formulaParser struct {
+	tokens []formulaToken
+	pos    int
+}
+
+func (p *formulaParser) peek() formulaToken {
+	return p.tokens[p.pos]
+}
+
+func (p *formulaParser) next() formulaToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "end" {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *formulaParser) unexpected(tok formulaToken) error {
+	if tok.kind == "end" {
+		return fmt.Errorf("%w: unexpected end of formula", ErrInvalidFormula)
+	}
+	return fmt.Errorf("%w: unexpected %q at %d", ErrInvalidFormula, tok.text, tok.pos)
+}
+
+// parseBinary parses a left associative chain of any of the given operators
+func (p *formulaParser) parseBinary(ops []string, operand func() (*formulaAST, error)) (*formulaAST, error) {
+	lhs, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		matched := false
+		for _, op := range ops {
+			if tok.kind == "op" && tok.text == op {
+				matched = true
+			}
+		}
+		if !matched {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &formulaAST{op: tok.text, args: []*formulaAST{lhs, rhs}}
+	}
+}
+
+func (p *formulaParser) parseOr() (*formulaAST, error) {
+	return p.parseBinary([]string{"||"}, p.parseAnd)
+}
+
+func (p *formulaParser) parseAnd() (*formulaAST, error) {
+	return p.parseBinary([]string{"&&"}, p.parseComparison)
+}
+
+func (p *formulaParser) parseComparison() (*formulaAST, error) {
+	return p.parseBinary([]string{"<", "<=", ">", ">=", "==", "!="}, p.parseSum)
+}
+
+func (p *formulaParser) parseSum() (*formulaAST, error) {
+	return p.parseBinary([]string{"+", "-"}, p.parseProduct)
+}
+
+func (p *formulaParser) parseProduct() (*formulaAST, error) {
+	return p.parseBinary([]string{"*", "/"}, p.parseUnary)
+}
+
+func (p *formulaParser) parseUnary() (*formulaAST, error) {
+	if tok := p.peek(); tok.kind == "op" && (tok.text == "-" || tok.text == "!" || tok.text == "+") {
+		p.next()
+		arg, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "-":
+			return &formulaAST{op: "neg", args: []*formulaAST{arg}}, nil
+		case "!":
+			return &formulaAST{op: "!", args: []*formulaAST{arg}}, nil
+		}
+		return arg, nil
+	}
+	return p.parsePower()
+}
+
+// parsePower parses ^, which is right associative and binds tighter than a unary minus on its left
+func (p *formulaParser) parsePower() (*formulaAST, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == "op" && tok.text == "^" {
+		p.next()
+		power, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &formulaAST{op: "^", args: []*formulaAST{base, power}}, nil
+	}
+	return base, nil
+}
+
+func (p *formulaParser) parsePrimary() (*formulaAST, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == "num":
+		return &formulaAST{op: "num", value: tok.value}, nil
+
+	case tok.kind == "name" && p.peek().text == "(":
+		p.next()
+		call := &formulaAST{op: "call", name: strings.ToLower(tok.text)}
+		for p.peek().text != ")" {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.peek().text != "," {
+				break
+			}
+			p.next()
+		}
+		if closing := p.next(); closing.text != ")" {
+			return nil, p.unexpected(closing)
+		}
+		return call, nil
+
+	case tok.kind == "name" && tok.text == "i":
+		return &formulaAST{op: "num", value: Complex{0, 1}}, nil
+
+	case tok.kind == "name":
+		return &formulaAST{op: "var", name: tok.text}, nil
+
+	case tok.text == "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.text != ")" {
+			return nil, p.unexpected(closing)
+		}
+		return expr, nil
+
+	case tok.text == "|":
+		// Comparisons aren't allowed between |s, as | would be ambiguous
+		expr, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.text != "|" {
+			return nil, p.unexpected(closing)
+		}
+		return &formulaAST{op: "call", name: "abs", args: []*formulaAST{expr}}, nil
+	}
+	return nil, p.unexpected(tok)
+}
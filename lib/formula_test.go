@@ -0,0 +1,58 @@
+package lib
+
+import (
+	"fmt"
+	"testing"
+)
+
+// pointFuncSink keeps benchmarked results from being optimised away
+var pointFuncSink float64
+
+// benchmarkPointFunc renders a grid of points around the mandelbrot set
+func benchmarkPointFunc(b *testing.B, pointFunc PointFunc) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				R, _, _, _ := pointFunc(-2+2.5*float64(x)/32, -1.25+2.5*float64(y)/32, 64)
+				pointFuncSink += R
+			}
+		}
+	}
+}
+
+func BenchmarkMandelbrot(b *testing.B) {
+	pointFunc, err := GetPointFunc("mandelbrot", "smoothcolor", nil, Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkPointFunc(b, pointFunc)
+}
+
+// BenchmarkFormulaMandelbrot is the same render as BenchmarkMandelbrot, but
+// from a formula
+func BenchmarkFormulaMandelbrot(b *testing.B) {
+	pointFunc, err := GetFormulaPointFunc("z^2 + c", "", "smoothcolor", nil, Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkPointFunc(b, pointFunc)
+}
+
+func TestFormulaPow(t *testing.T) {
+	for _, n := range []float64{2, 3, 7, 20, -2, 0, 2.5} {
+		formula := fmt.Sprintf("z^%v", n)
+		f, err := CompileFormula(formula, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, z := range complexGrid {
+			vars := append([]Complex{}, f.vars...)
+			vars[formulaZ] = FromComplex128(z)
+			got, want := f.iterate(vars).Complex128(), FromComplex128(z).Pow(n).Complex128()
+			if !closeTo(got, want) {
+				t.Errorf("%s for z = %v is %v, want %v", formula, z, got, want)
+			}
+		}
+	}
+}
@@ -12,35 +12,98 @@ var (
 	ErrInvalidFractal      = errors.New("invalid fractal name")
 	ErrInvalidColor        = errors.New("invalid color scheme name")
 	ErrColorNotImplemented = errors.New("color scheme name valid but not implemented")
+	ErrInvalidBailout      = errors.New("bailout radius must not be negative")
+	ErrDuplicateFractal    = errors.New("a fractal with that name already exists")
+	ErrDuplicateColor      = errors.New("a color scheme with that name already exists")
+	ErrIncompleteFractal   = errors.New("a fractal needs a Fn and a default color scheme from its ColorSchemes")
 )
 
-type fractalFunc func(color colorFunc, constants []float64) PointFunc
+// FractalFunc returns a PointFunc for the fractal, given the color scheme,
+// the values of its parameters and the bailout to use.
+type FractalFunc func(color ColorFunc, params Params, bailout Bailout) PointFunc
 
 // Fractal gontains everything you need to get a colorized point function for our generator
 type Fractal struct {
 	Description        string
-	Constants          int
+	Params             []Param
 	ColorSchemes       []string
 	DefaultColorScheme string
-	Fn                 fractalFunc
+	Fn                 FractalFunc
+
+	// Map, if set, returns the fractal's map z -> f(z, c), iterated from
+	// z = 0, for density renderers such as the buddhabrot.
+	Map func(params Params) func(z, c Complex) Complex
+
+	// Julia is the name of the fractal's julia counterpart, if it has one,
+	// whose parameters are c followed by this fractal's parameters.
+	Julia string
+
+	// Bailout is the default bailout radius, or DefaultBailoutRadius if it's 0.
+	Bailout float64
+
+	// Check, if set, validates the parameters together, after each has been checked on its own.
+	Check func(params Params) error
 }
 
 // String outputs basic info for the help screen
 func (f Fractal) String() string {
-	return fmt.Sprintf("%s\nColor Schemes: %s", f.Description, strings.Join(f.ColorSchemes, ", "))
+	str := f.Description
+	if len(f.Params) > 0 {
+		str += "\nParameters:"
+		for _, param := range f.Params {
+			str += "\n\t" + param.String()
+		}
+	}
+	str += "\nColor Schemes: " + strings.Join(f.ColorSchemes, ", ")
+	if f.Julia != "" {
+		str += "\nJulia sets: " + f.Julia
+	}
+	return str
+}
+
+// Options holds the per-render settings shared by every fractal
+type Options struct {
+	// PaletteOffset shifts the colour scheme along by this many iterations,
+	// which is handy for cycling a palette over the frames of an animation.
+	PaletteOffset float64
+
+	// Strings are the string constants, for fractals that take them.
+	Strings []string
+	// Params are named parameter values, e.g. "power": "3", which take
+	// precedence over the constants and strings.
+	Params map[string]string
+
+	// BailoutRadius overrides the fractal's own bailout radius, unless it's 0.
+	BailoutRadius float64
+	// BailoutNorm is how the size of z is measured against the bailout radius.
+	BailoutNorm Norm
 }
 
+// Parameters shared by many fractals
+var (
+	juliaC      = Param{Name: "c", Type: ParamComplex, Default: "-0.2+0.65i", Help: "the constant added each iteration"}
+	powerParam  = Param{Name: "power", Type: ParamFloat, Default: "3", Help: "the power to which z is raised"}
+	powerParam2 = Param{Name: "power", Type: ParamFloat, Default: "2", Help: "the power to which z is raised"}
+	relaxation  = Param{Name: "relaxation", Type: ParamFloat, Default: "1", Help: "scales each step, 1 for plain newton's method"}
+)
+
 // GetPointFunc will check for valid fractalname and colorname
 // returns a pointFunc if we're good to go
-func GetPointFunc(fractalName, colorName string, constants []float64) (PointFunc, error) {
+func GetPointFunc(fractalName, colorName string, constants []float64, opts Options) (PointFunc, error) {
 	frac, err := GetFractal(fractalName)
 	if err != nil {
 		return nil, err
 	}
 
-	// check constants
-	if len(constants) != frac.Constants {
-		return nil, errors.New("invalid number of constants")
+	return frac.pointFunc(colorName, constants, opts)
+}
+
+// pointFunc checks the constants and colorname are valid for this fractal,
+// and returns a pointFunc if we're good to go
+func (frac *Fractal) pointFunc(colorName string, constants []float64, opts Options) (PointFunc, error) {
+	params, err := frac.params(constants, opts.Strings, opts.Params)
+	if err != nil {
+		return nil, err
 	}
 
 	// colorNames should always be lowercased
@@ -70,8 +133,30 @@ func GetPointFunc(fractalName, colorName string, constants []float64) (PointFunc
 	if !colorFuncExists {
 		return nil, ErrColorNotImplemented
 	}
+	if opts.PaletteOffset != 0 {
+		colorFunc = withPaletteOffset(colorFunc, opts.PaletteOffset)
+	}
+
+	bailout, err := frac.bailout(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	return frac.Fn(colorFunc, constants), nil
+	return frac.Fn(colorFunc, params, bailout), nil
+}
+
+// bailout works out the bailout for this fractal from the options
+func (frac *Fractal) bailout(opts Options) (Bailout, error) {
+	if opts.BailoutRadius < 0 {
+		return Bailout{}, ErrInvalidBailout
+	}
+	bailout := Bailout{frac.Bailout, opts.BailoutNorm}
+	if opts.BailoutRadius != 0 {
+		bailout.Radius = opts.BailoutRadius
+	} else if bailout.Radius == 0 {
+		bailout.Radius = DefaultBailoutRadius
+	}
+	return bailout, nil
 }
 
 // GetFractal returns a fractal if the name is valid
@@ -85,25 +170,79 @@ func GetFractal(fractalName string) (*Fractal, error) {
 	return frac, nil
 }
 
+// RegisterFractal adds a fractal to Fractals, so that it can be rendered and
+// is listed by romanesgo help like the built in ones. It's meant to be called
+// from an init func, as Fractals isn't safe to change while rendering.
+func RegisterFractal(name string, frac *Fractal) error {
+	name = strings.ToLower(name)
+	if _, exists := Fractals[name]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateFractal, name)
+	}
+	if frac == nil || frac.Fn == nil || !contains(frac.ColorSchemes, frac.DefaultColorScheme) {
+		return fmt.Errorf("%w: %s", ErrIncompleteFractal, name)
+	}
+	Fractals[name] = frac
+	return nil
+}
+
+// RegisterColorScheme adds a color scheme, and adds it to the color schemes of
+// any fractals named, so it can be used with fractals that are already
+// registered. Like RegisterFractal, it's meant to be called from an init func.
+func RegisterColorScheme(name string, color ColorFunc, fractalNames ...string) error {
+	name = strings.ToLower(name)
+	if _, exists := colorSchemes[name]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateColor, name)
+	}
+	fracs := make([]*Fractal, len(fractalNames))
+	for key, fractalName := range fractalNames {
+		frac, err := GetFractal(fractalName)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, fractalName)
+		}
+		fracs[key] = frac
+	}
+
+	colorSchemes[name] = color
+	for _, frac := range fracs {
+		if !contains(frac.ColorSchemes, name) {
+			frac.ColorSchemes = append(frac.ColorSchemes, name)
+		}
+	}
+	return nil
+}
+
+// contains checks if a list of names contains the name
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Fractals is a map of the available fractals in this program
 var Fractals = map[string]*Fractal{
 	// Mandelbrot based fractals
 	"mandelbrot": &Fractal{
 		Description:        "Classic mandelbrot function.",
-		Constants:          0,
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Julia:              "julia",
+		Map: func(params Params) func(z, c Complex) Complex {
+			return absFoldMap(0, 2)
+		},
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				c := complex{xCoord, yCoord}
-				z := complex{0.0, 0.0}
+				c := Complex{xCoord, yCoord}
+				z := Complex{0.0, 0.0}
 				iterations := 0
 
-				iterate := func(z complex) complex {
-					return z.mul(z).add(c)
+				iterate := func(z Complex) Complex {
+					return z.Mul(z).Add(c)
 				}
 
-				for iterations = 0; z.abs() <= 2 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -113,6 +252,7 @@ var Fractals = map[string]*Fractal{
 					map[string]interface{}{
 						"z":        z,
 						"iterator": iterate,
+						"bailout":  bailout,
 					},
 				)
 			}
@@ -120,21 +260,26 @@ var Fractals = map[string]*Fractal{
 	},
 
 	"multibrot": &Fractal{
-		Description:        "Classic multibrot function.\nConstant is the power to which z is raised.",
-		Constants:          1,
+		Description:        "Classic multibrot function.",
+		Params:             []Param{powerParam},
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Julia:              "multijulia",
+		Map: func(params Params) func(z, c Complex) Complex {
+			return absFoldMap(0, params.Float("power"))
+		},
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			pow := powerFunc(params.Float("power"))
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				c := complex{xCoord, yCoord}
-				z := complex{0.0, 0.0}
+				c := Complex{xCoord, yCoord}
+				z := Complex{0.0, 0.0}
 				iterations := 0
 
-				iterate := func(z complex) complex {
-					return z.pow(constants[0]).add(c)
+				iterate := func(z Complex) Complex {
+					return pow(z).Add(c)
 				}
 
-				for iterations = 0; z.abs() <= 2 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -142,7 +287,8 @@ var Fractals = map[string]*Fractal{
 					iterations,
 					iterationCap,
 					map[string]interface{}{
-						"z": z,
+						"z":       z,
+						"bailout": bailout,
 					},
 				)
 			}
@@ -151,21 +297,21 @@ var Fractals = map[string]*Fractal{
 
 	// Julia set based fractals
 	"julia": &Fractal{
-		Description:        "Classic Julia function.\nThe two constants are the real and imaginary components of C.",
-		Constants:          2,
+		Description:        "Classic Julia function.",
+		Params:             []Param{juliaC},
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				c := complex{constants[0], constants[1]}
-				z := complex{xCoord, yCoord}
+				z := Complex{xCoord, yCoord}
 				iterations := 0
 
-				iterate := func(z complex) complex {
-					return z.mul(z).add(c)
+				iterate := func(z Complex) Complex {
+					return z.Mul(z).Add(c)
 				}
 
-				for iterations = 0; z.abs() <= 2 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -175,6 +321,7 @@ var Fractals = map[string]*Fractal{
 					map[string]interface{}{
 						"z":        z,
 						"iterator": iterate,
+						"bailout":  bailout,
 					},
 				)
 			}
@@ -182,21 +329,21 @@ var Fractals = map[string]*Fractal{
 	},
 
 	"multijulia": &Fractal{
-		Description:        "Classic multijulia function.\nThe first two constants are the real and imaginary components of C, the third constant is the power to which z is raised.",
-		Constants:          3,
+		Description:        "Classic multijulia function.",
+		Params:             []Param{juliaC, powerParam},
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c, pow := params.Complex("c"), powerFunc(params.Float("power"))
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				c := complex{constants[0], constants[1]}
-				z := complex{xCoord, yCoord}
+				z := Complex{xCoord, yCoord}
 				iterations := 0
 
-				iterate := func(z complex) complex {
-					return z.pow(constants[2]).add(c)
+				iterate := func(z Complex) Complex {
+					return pow(z).Add(c)
 				}
 
-				for iterations = 0; z.abs() <= 2 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -204,7 +351,8 @@ var Fractals = map[string]*Fractal{
 					iterations,
 					iterationCap,
 					map[string]interface{}{
-						"z": z,
+						"z":       z,
+						"bailout": bailout,
 					},
 				)
 			}
@@ -214,23 +362,26 @@ var Fractals = map[string]*Fractal{
 	// Burning ship based fractals
 	"burningship": &Fractal{
 		Description:        "Classic burning ship function.",
-		Constants:          0,
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Julia:              "burningshipjulia",
+		Map: func(params Params) func(z, c Complex) Complex {
+			return absFoldMap(foldReal|foldImag, 2)
+		},
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				z := complex{0, 0}
-				c := complex{xCoord, yCoord}
+				z := Complex{0, 0}
+				c := Complex{xCoord, yCoord}
 				iterations := 0
 
-				iterate := func(z complex) (r complex) {
-					r.real = math.Abs(z.real)
-					r.imag = math.Abs(z.imag)
-					r = r.mul(r).add(c)
+				iterate := func(z Complex) (r Complex) {
+					r.Real = math.Abs(z.Real)
+					r.Imag = math.Abs(z.Imag)
+					r = r.Mul(r).Add(c)
 					return r
 				}
 
-				for iterations = 0; z.abs() <= 2 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -240,6 +391,7 @@ var Fractals = map[string]*Fractal{
 					map[string]interface{}{
 						"z":        z,
 						"iterator": iterate,
+						"bailout":  bailout,
 					},
 				)
 			}
@@ -248,23 +400,26 @@ var Fractals = map[string]*Fractal{
 
 	"birdofprey": &Fractal{
 		Description:        "Classic burning ship function, with z raised to the power of 3 in lieu of 2.\nProduces a fractal likened to Klingon birds of prey from Star Trek.",
-		Constants:          0,
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Julia:              "birdofpreyjulia",
+		Map: func(params Params) func(z, c Complex) Complex {
+			return absFoldMap(foldReal|foldImag, 3)
+		},
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				z := complex{0, 0}
-				c := complex{xCoord, yCoord}
+				z := Complex{0, 0}
+				c := Complex{xCoord, yCoord}
 				iterations := 0
 
-				iterate := func(z complex) (r complex) {
-					r.real = math.Abs(z.real)
-					r.imag = math.Abs(z.imag)
-					r = r.mul(r).mul(r).add(c)
+				iterate := func(z Complex) (r Complex) {
+					r.Real = math.Abs(z.Real)
+					r.Imag = math.Abs(z.Imag)
+					r = r.Mul(r).Mul(r).Add(c)
 					return r
 				}
 
-				for iterations = 0; z.abs() <= 2 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -274,6 +429,7 @@ var Fractals = map[string]*Fractal{
 					map[string]interface{}{
 						"z":        z,
 						"iterator": iterate,
+						"bailout":  bailout,
 					},
 				)
 			}
@@ -281,24 +437,29 @@ var Fractals = map[string]*Fractal{
 	},
 
 	"multiburningship": &Fractal{
-		Description:        "Classic burning ship function, with z raised to the power of a constant in lieu of 2.",
-		Constants:          1,
+		Description:        "Classic burning ship function, with z raised to any power in lieu of 2.",
+		Params:             []Param{powerParam},
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Julia:              "multiburningshipjulia",
+		Map: func(params Params) func(z, c Complex) Complex {
+			return absFoldMap(foldReal|foldImag, params.Float("power"))
+		},
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			pow := powerFunc(params.Float("power"))
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				z := complex{0, 0}
-				c := complex{xCoord, yCoord}
+				z := Complex{0, 0}
+				c := Complex{xCoord, yCoord}
 				iterations := 0
 
-				iterate := func(z complex) (r complex) {
-					r.real = math.Abs(z.real)
-					r.imag = math.Abs(z.imag)
-					r = r.pow(constants[0]).add(c)
+				iterate := func(z Complex) (r Complex) {
+					r.Real = math.Abs(z.Real)
+					r.Imag = math.Abs(z.Imag)
+					r = pow(r).Add(c)
 					return r
 				}
 
-				for iterations = 0; z.abs() <= 2 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -306,32 +467,60 @@ var Fractals = map[string]*Fractal{
 					iterations,
 					iterationCap,
 					map[string]interface{}{
-						"z": z,
+						"z":       z,
+						"bailout": bailout,
 					},
 				)
 			}
 		},
 	},
 
+	"burningshipjulia": &Fractal{
+		Description:        "Julia sets of the burning ship fractal.",
+		Params:             []Param{juliaC},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
+			return absFoldFractal(color, bailout, foldReal|foldImag, 2, &c)
+		},
+	},
+
+	"birdofpreyjulia": &Fractal{
+		Description:        "Julia sets of the bird of prey fractal.",
+		Params:             []Param{juliaC},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
+			return absFoldFractal(color, bailout, foldReal|foldImag, 3, &c)
+		},
+	},
+
+	"multiburningshipjulia": absFoldJuliaVariant("Julia sets of the multiburningship fractal.", foldReal|foldImag),
+
 	// Tricorn based fractals
 	"tricorn": &Fractal{
 		Description:        "Classic tricorn function.",
-		Constants:          0,
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Julia:              "tricornjulia",
+		Map: func(params Params) func(z, c Complex) Complex {
+			return absFoldMap(foldConj, 2)
+		},
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				c := complex{xCoord, yCoord}
-				z := complex{0.0, 0.0}
+				c := Complex{xCoord, yCoord}
+				z := Complex{0.0, 0.0}
 				iterations := 0
 
-				iterate := func(z complex) (r complex) {
-					r = z.conj()
-					r = r.mul(r).add(c)
+				iterate := func(z Complex) (r Complex) {
+					r = z.Conj()
+					r = r.Mul(r).Add(c)
 					return r
 				}
 
-				for iterations = 0; z.abs() <= 2 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -341,6 +530,7 @@ var Fractals = map[string]*Fractal{
 					map[string]interface{}{
 						"z":        z,
 						"iterator": iterate,
+						"bailout":  bailout,
 					},
 				)
 			}
@@ -348,23 +538,28 @@ var Fractals = map[string]*Fractal{
 	},
 
 	"multicorn": &Fractal{
-		Description:        "Classic multicorn function. Constant is the power to which the conjugate of z is raised.",
-		Constants:          1,
+		Description:        "Classic multicorn function, with the conjugate of z raised to any power.",
+		Params:             []Param{powerParam},
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Julia:              "multicornjulia",
+		Map: func(params Params) func(z, c Complex) Complex {
+			return absFoldMap(foldConj, params.Float("power"))
+		},
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			pow := powerFunc(params.Float("power"))
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				c := complex{xCoord, yCoord}
-				z := complex{0.0, 0.0}
+				c := Complex{xCoord, yCoord}
+				z := Complex{0.0, 0.0}
 				iterations := 0
 
-				iterate := func(z complex) (r complex) {
-					r = z.conj()
-					r = r.pow(constants[0]).add(c)
+				iterate := func(z Complex) (r Complex) {
+					r = z.Conj()
+					r = pow(r).Add(c)
 					return r
 				}
 
-				for iterations = 0; z.abs() <= 2 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -372,33 +567,217 @@ var Fractals = map[string]*Fractal{
 					iterations,
 					iterationCap,
 					map[string]interface{}{
-						"z": z,
+						"z":       z,
+						"bailout": bailout,
 					},
 				)
 			}
 		},
 	},
 
+	"tricornjulia": &Fractal{
+		Description:        "Julia sets of the tricorn fractal.",
+		Params:             []Param{juliaC},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
+			return absFoldFractal(color, bailout, foldConj, 2, &c)
+		},
+	},
+
+	"multicornjulia": absFoldJuliaVariant("Julia sets of the multicorn fractal.", foldConj),
+
 	// Collatz conjecture based fractals
 	"collatz": &Fractal{
-		Description:        "The Collatz fractal.\nThe constant value is the absolute value after which the sequence will be assumed to have escaped.",
-		Constants:          0,
+		Description:        "The Collatz fractal.\nThe sequence is assumed to have escaped once it passes the bailout radius, which is the largest float64 by default.",
 		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
 		DefaultColorScheme: "simplegrayscale",
-		Fn: func(color colorFunc, constants []float64) PointFunc {
+		Bailout:            math.MaxFloat64,
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
 			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
-				z := complex{xCoord, yCoord}
+				z := Complex{xCoord, yCoord}
 				iterations := 0
 
-				iterate := func(z complex) (r complex) {
-					cossq := z.mul(complex{math.Pi / 2, 0}).cos().sq()
-					sinsq := z.mul(complex{math.Pi / 2, 0}).sin().sq()
-					r = cossq.mul(z.mul(complex{0.5, 0})).add(
-						sinsq.mul(z.mul(complex{3.0, 0}).add(complex{1.0, 0})))
+				iterate := func(z Complex) (r Complex) {
+					cossq := z.Mul(Complex{math.Pi / 2, 0}).Cos().Sq()
+					sinsq := z.Mul(Complex{math.Pi / 2, 0}).Sin().Sq()
+					r = cossq.Mul(z.Mul(Complex{0.5, 0})).Add(
+						sinsq.Mul(z.Mul(Complex{3.0, 0}).Add(Complex{1.0, 0})))
 					return r
 				}
 
-				for iterations = 0; z.abs() < math.MaxFloat64 && iterations < iterationCap; iterations++ {
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
+					z = iterate(z)
+				}
+
+				return color(
+					iterations,
+					iterationCap,
+					map[string]interface{}{
+						"z":       z,
+						"bailout": bailout,
+					},
+				)
+			}
+		},
+	},
+	// Abs-folded mandelbrot variants, see absfold.go
+	"absfold": &Fractal{
+		Description:        "The mandelbrot set with absolute values of z's components taken, or z conjugated, around raising it to a power.",
+		Params:             []Param{absFoldMask, powerParam2},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Julia:              "absfoldjulia",
+		Map: func(params Params) func(z, c Complex) Complex {
+			return absFoldMap(absFold(params.Int("mask")), params.Float("power"))
+		},
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			return absFoldFractal(color, bailout, absFold(params.Int("mask")), params.Float("power"), nil)
+		},
+	},
+
+	"absfoldjulia": &Fractal{
+		Description:        "Julia sets of the absfold fractal.",
+		Params:             []Param{juliaC, absFoldMask, powerParam2},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
+			return absFoldFractal(color, bailout, absFold(params.Int("mask")), params.Float("power"), &c)
+		},
+	},
+
+	"celtic":                 absFoldVariant("The celtic fractal, the mandelbrot set with the absolute value of the real component of z taken after raising it to the power.", foldPowReal, "celticjulia"),
+	"celticjulia":            absFoldJuliaVariant("Julia sets of the celtic fractal.", foldPowReal),
+	"buffalo":                absFoldVariant("The buffalo fractal, the mandelbrot set with the absolute values of both components of z taken after raising it to the power.", foldPowReal|foldPowImag, "buffalojulia"),
+	"buffalojulia":           absFoldJuliaVariant("Julia sets of the buffalo fractal.", foldPowReal|foldPowImag),
+	"perpendicular":          absFoldVariant("The perpendicular mandelbrot set, with z -> conj(|re(z)| + i*im(z))^n + c.", foldReal|foldConj, "perpendicularjulia"),
+	"perpendicularjulia":     absFoldJuliaVariant("Julia sets of the perpendicular mandelbrot set.", foldReal|foldConj),
+	"perpendicularship":      absFoldVariant("The perpendicular burning ship, with z -> conj(re(z) + i*|im(z)|)^n + c.", foldImag|foldConj, "perpendicularshipjulia"),
+	"perpendicularshipjulia": absFoldJuliaVariant("Julia sets of the perpendicular burning ship.", foldImag|foldConj),
+	"heart":                  absFoldVariant("The heart mandelbrot set, with z -> (|re(z)| + i*im(z))^n + c.", foldReal, "heartjulia"),
+	"heartjulia":             absFoldJuliaVariant("Julia sets of the heart mandelbrot set.", foldReal),
+
+	// Fractals with more than one state variable, or a finite attractor
+	"phoenix": &Fractal{
+		Description:        "The phoenix fractal, z -> z^2 + c + p*y with y the previous value of z, and c the point being rendered.",
+		Params:             []Param{phoenixP},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Julia:              "phoenixjulia",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			return phoenix(color, bailout, params.Complex("p"), nil)
+		},
+	},
+
+	"phoenixjulia": &Fractal{
+		Description:        "The phoenix fractal, z -> z^2 + c + p*y with y the previous value of z, and z starting at the point being rendered.",
+		Params:             []Param{{Name: "c", Type: ParamComplex, Default: "0.5667", Help: "the constant added each iteration"}, phoenixP},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
+			return phoenix(color, bailout, params.Complex("p"), &c)
+		},
+	},
+
+	"magnet1": &Fractal{
+		Description:        "The type I magnet fractal, z -> ((z^2 + c - 1) / (2z + c - 2))^2, which escapes or converges to 1.",
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "rootshaded", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "wackygrayscale",
+		Bailout:            100,
+		Julia:              "magnet1julia",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			return magnet(color, bailout, magnetI, nil)
+		},
+	},
+
+	"magnet1julia": &Fractal{
+		Description:        "The julia set of the type I magnet fractal.",
+		Params:             []Param{{Name: "c", Type: ParamComplex, Default: "1.6+0.5i", Help: "the constant added each iteration"}},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "rootshaded", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "wackygrayscale",
+		Bailout:            100,
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
+			return magnet(color, bailout, magnetI, &c)
+		},
+	},
+
+	"magnet2": &Fractal{
+		Description:        "The type II magnet fractal, z -> ((z^3 + 3(c-1)z + (c-1)(c-2)) / (3z^2 + 3(c-2)z + (c-1)(c-2) + 1))^2, which escapes or converges to 1.",
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "rootshaded", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "wackygrayscale",
+		Bailout:            100,
+		Julia:              "magnet2julia",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			return magnet(color, bailout, magnetII, nil)
+		},
+	},
+
+	"magnet2julia": &Fractal{
+		Description:        "The julia set of the type II magnet fractal.",
+		Params:             []Param{{Name: "c", Type: ParamComplex, Default: "1.2+0.3i", Help: "the constant added each iteration"}},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "rootshaded", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "wackygrayscale",
+		Bailout:            100,
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
+			return magnet(color, bailout, magnetII, &c)
+		},
+	},
+
+	"lambda": &Fractal{
+		Description:        "The lambda fractal of the logistic map, z -> lambda*z*(1 - z), with lambda the point being rendered and z starting at 0.5.",
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Bailout:            100,
+		Julia:              "lambdajulia",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+				lambda := Complex{xCoord, yCoord}
+				z := Complex{0.5, 0}
+				iterations := 0
+
+				iterate := func(z Complex) Complex {
+					return lambda.Mul(z).Mul(one.Sub(z))
+				}
+
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
+					z = iterate(z)
+				}
+
+				return color(
+					iterations,
+					iterationCap,
+					map[string]interface{}{
+						"z":        z,
+						"iterator": iterate,
+						"bailout":  bailout,
+					},
+				)
+			}
+		},
+	},
+
+	"lambdajulia": &Fractal{
+		Description:        "The julia set of the logistic map, z -> lambda*z*(1 - z), with z starting at the point being rendered.",
+		Params:             []Param{{Name: "c", Type: ParamComplex, Default: "1+0.1i", Help: "lambda"}},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "smoothgrayscale", "smoothcolor", "smoothcolor2", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Bailout:            100,
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			lambda := params.Complex("c")
+			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+				z := Complex{xCoord, yCoord}
+				iterations := 0
+
+				iterate := func(z Complex) Complex {
+					return lambda.Mul(z).Mul(one.Sub(z))
+				}
+
+				for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
 					z = iterate(z)
 				}
 
@@ -406,10 +785,85 @@ var Fractals = map[string]*Fractal{
 					iterations,
 					iterationCap,
 					map[string]interface{}{
-						"z": z,
+						"z":        z,
+						"iterator": iterate,
+						"bailout":  bailout,
 					},
 				)
 			}
 		},
 	},
+
+	// Newton's method based fractals
+	"newton": &Fractal{
+		Description:        "Newton's method for z^n - 1.",
+		Params:             []Param{newtonN, relaxation},
+		ColorSchemes:       []string{"rootshaded", "rootflat", "simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "rootshaded",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			p, roots := unityPolynomial(params.Int("n"))
+			return newton(color, p, roots, Complex{params.Float("relaxation"), 0})
+		},
+	},
+
+	"newtonpoly": &Fractal{
+		Description:        "Newton's method for any polynomial.",
+		Params:             []Param{relaxation, newtonCoefficients},
+		Check:              checkCoefficients,
+		ColorSchemes:       []string{"rootshaded", "rootflat", "simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "rootshaded",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			coefficients := params.Floats("coefficients")
+			p := make(polynomial, len(coefficients))
+			for key, coeff := range coefficients {
+				p[key] = Complex{coeff, 0}
+			}
+			return newton(color, p, p.roots(), Complex{params.Float("relaxation"), 0})
+		},
+	},
+
+	"newtonroots": &Fractal{
+		Description:        "Newton's method for the polynomial with the given roots.",
+		Params:             []Param{relaxation, newtonRoots},
+		ColorSchemes:       []string{"rootshaded", "rootflat", "simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "rootshaded",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			roots := params.Complexes("roots")
+			return newton(color, polynomialFromRoots(roots), roots, Complex{params.Float("relaxation"), 0})
+		},
+	},
+
+	"nova": &Fractal{
+		Description:        "The Nova fractal: newton's method for z^n - 1, relaxed, with c added each step, starting from z = 1.",
+		Params:             []Param{newtonN, relaxation},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Julia:              "novajulia",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			return nova(color, bailout, params.Int("n"), Complex{params.Float("relaxation"), 0}, nil)
+		},
+	},
+
+	"novajulia": &Fractal{
+		Description:        "Julia sets of the Nova fractal, starting from z at the point being rendered.",
+		Params:             []Param{{Name: "c", Type: ParamComplex, Default: "0.3-0.1i", Help: "the constant added each iteration"}, newtonN, relaxation},
+		ColorSchemes:       []string{"simplegrayscale", "zgrayscale", "wackyrainbow", "wackygrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			c := params.Complex("c")
+			return nova(color, bailout, params.Int("n"), Complex{params.Float("relaxation"), 0}, &c)
+		},
+	},
+
+	// Fractals of real maps
+	"lyapunov": &Fractal{
+		Description:        "The lyapunov fractal of the logistic map x -> r*x*(1 - x), with r switching between a along the x axis and b up the y axis, e.g. -x=3 -y=3 -z=1.\nPoints are colored by the lyapunov exponent, which is negative where the map is stable and positive where it's chaotic.",
+		Params:             []Param{{Name: "sequence", Type: ParamString, Default: "AB", Help: "the sequence of a and b, e.g. AB or BBBBBBAAAAAA"}},
+		Check:              checkSequence,
+		ColorSchemes:       []string{"lyapunov", "lyapunovgrayscale"},
+		DefaultColorScheme: "lyapunov",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			return lyapunov(color, parseSequence(params.String("sequence")))
+		},
+	},
 }
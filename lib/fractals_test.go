@@ -0,0 +1,100 @@
+package lib
+
+import (
+	"errors"
+	"testing"
+)
+
+// testFractal returns a complete fractal, which can be registered
+func testFractal() *Fractal {
+	return &Fractal{
+		Description:        "A test fractal.",
+		ColorSchemes:       []string{"simplegrayscale"},
+		DefaultColorScheme: "simplegrayscale",
+		Fn: func(color ColorFunc, params Params, bailout Bailout) PointFunc {
+			return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+				return color(0, iterationCap, map[string]interface{}{"z": Complex{}, "bailout": bailout})
+			}
+		},
+	}
+}
+
+func TestRegisterFractal(t *testing.T) {
+	defer delete(Fractals, "testfractal")
+	if err := RegisterFractal("TestFractal", testFractal()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetPointFunc("testfractal", "", nil, Options{}); err != nil {
+		t.Errorf("the registered fractal can't be rendered: %v", err)
+	}
+
+	for _, name := range []string{"testfractal", "Mandelbrot"} {
+		if err := RegisterFractal(name, testFractal()); !errors.Is(err, ErrDuplicateFractal) {
+			t.Errorf("registering %s again got %v, want %v", name, err, ErrDuplicateFractal)
+		}
+	}
+}
+
+func TestRegisterIncompleteFractal(t *testing.T) {
+	noFn, noDefault, wrongDefault := testFractal(), testFractal(), testFractal()
+	noFn.Fn = nil
+	noDefault.DefaultColorScheme = ""
+	wrongDefault.DefaultColorScheme = "smoothcolor"
+	for name, frac := range map[string]*Fractal{
+		"nil":          nil,
+		"noFn":         noFn,
+		"noDefault":    noDefault,
+		"wrongDefault": wrongDefault,
+	} {
+		if err := RegisterFractal(name, frac); !errors.Is(err, ErrIncompleteFractal) {
+			t.Errorf("registering %s got %v, want %v", name, err, ErrIncompleteFractal)
+		}
+		if _, err := GetFractal(name); err == nil {
+			delete(Fractals, name)
+			t.Errorf("%s was registered", name)
+		}
+	}
+}
+
+func TestRegisterColorScheme(t *testing.T) {
+	stripes := func(iterations, iterationCap int, kwargs map[string]interface{}) (R, G, B, A float64) {
+		return float64(iterations%2) * 255, 0, 0, 255
+	}
+	mandelbrot, julia := Fractals["mandelbrot"], Fractals["julia"]
+	defer func(mandelbrotSchemes, juliaSchemes []string) {
+		delete(colorSchemes, "stripes")
+		mandelbrot.ColorSchemes, julia.ColorSchemes = mandelbrotSchemes, juliaSchemes
+	}(mandelbrot.ColorSchemes, julia.ColorSchemes)
+
+	if err := RegisterColorScheme("Stripes", stripes, "mandelbrot", "Julia"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"mandelbrot", "julia"} {
+		if _, err := GetPointFunc(name, "stripes", nil, Options{}); err != nil {
+			t.Errorf("the registered colour scheme can't be used with %s: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"stripes", "SmoothColor"} {
+		if err := RegisterColorScheme(name, stripes); !errors.Is(err, ErrDuplicateColor) {
+			t.Errorf("registering %s again got %v, want %v", name, err, ErrDuplicateColor)
+		}
+	}
+}
+
+func TestRegisterColorSchemeUnknownFractal(t *testing.T) {
+	mandelbrotSchemes := len(Fractals["mandelbrot"].ColorSchemes)
+	err := RegisterColorScheme("nosuchscheme", nil, "mandelbrot", "nosuchfractal")
+	if !errors.Is(err, ErrInvalidFractal) {
+		t.Errorf("got %v, want %v", err, ErrInvalidFractal)
+	}
+
+	// Nothing is registered unless all of it can be
+	if _, exists := colorSchemes["nosuchscheme"]; exists {
+		delete(colorSchemes, "nosuchscheme")
+		t.Error("the colour scheme was registered")
+	}
+	if len(Fractals["mandelbrot"].ColorSchemes) != mandelbrotSchemes {
+		t.Error("the colour scheme was added to mandelbrot")
+	}
+}
@@ -0,0 +1,163 @@
+package lib
+
+import (
+	"math"
+	"sync"
+)
+
+// reuseTolerance is how far, in pixels, a pixel can be from one of the cached
+// frame's and still be taken to be the same pixel
+const reuseTolerance = 1e-6
+
+// FrameCache keeps the last frame rendered, so the next frame can reuse the
+// pixels the two have in common rather than rendering them again. Panning
+// keeps the pixels still in view, zooming in by a whole factor n keeps every
+// nth pixel of every nth row, and zooming out by one keeps every pixel still
+// in view.
+//
+// The cache can't tell point funcs apart, so frames are kept with a key,
+// which has to be different for different fractals, constants and colour
+// schemes, and pixels are only reused from a frame with the same key. The
+// generators must have the same iterations, samples, transform and clip too,
+// and with more than one sample only panning keeps pixels, as zooming moves
+// the samples within them. Reused pixels are of the same points to within
+// rounding, so the odd pixel on the very edge of a set can come out differently
+// to rendering it afresh. It's safe for concurrent use.
+type FrameCache struct {
+	mu  sync.Mutex
+	key string
+	gen *Generator
+}
+
+// NewFrameCache returns an empty frame cache
+func NewFrameCache() *FrameCache {
+	return &FrameCache{}
+}
+
+// Prepare fills in the pixels the generator has in common with the cached
+// frame, if it has the same key, and the generator then leaves them be when
+// it generates. So that as many pixels as possible line up, the generator's
+// centre is moved by up to half a pixel of the cached frame; View says where
+// it ends up. Prepare returns the number of pixels filled in.
+func (c *FrameCache) Prepare(key string, gen *Generator) int {
+	c.mu.Lock()
+	prev, same := c.gen, c.key == key
+	c.mu.Unlock()
+	if prev == nil || !same || !gen.sameRender(*prev) {
+		return 0
+	}
+
+	prevSize, size := pixelSize(*prev), pixelSize(*gen)
+	scale := size / prevSize
+	period, ok := reusePeriod(scale, gen.samples)
+	if !ok {
+		return 0
+	}
+
+	// Nudge the centre so the first pixel lines up with the cached frame's pixels
+	origin := gen.prevPixel(*prev, scale)
+	for axis := range origin {
+		origin[axis] = (math.Round(origin[axis]/period)*period - origin[axis]) * prevSize
+	}
+	m := gen.matrix
+	gen.xPos += origin[0]*m[0] + origin[1]*m[1]
+	gen.yPos += origin[0]*m[2] + origin[1]*m[3]
+
+	return gen.reuse(*prev, scale)
+}
+
+// Keep keeps a copy of the generator's frame, with the key, once it's been
+// rendered in full, replacing the cached frame.
+func (c *FrameCache) Keep(key string, gen Generator) {
+	img := *gen.Img
+	img.Pix = append([]uint8{}, gen.Img.Pix...)
+	gen.Img = &img
+	gen.known = nil
+
+	c.mu.Lock()
+	c.key, c.gen = key, &gen
+	c.mu.Unlock()
+}
+
+// pixelSize is the width of a pixel on the complex plane, before the transform
+func pixelSize(f Generator) float64 {
+	return (2 / f.scaler) / f.zoom
+}
+
+// sameRender checks a generator renders each point of the plane the same as
+// another, and maps pixels to the plane in a way that can be inverted
+func (f Generator) sameRender(prev Generator) bool {
+	if f.expMap || prev.expMap || f.iterationCap != prev.iterationCap || f.samples != prev.samples || f.matrix != prev.matrix {
+		return false
+	}
+	if (f.clip == nil) != (prev.clip == nil) || (f.clip != nil && *f.clip != *prev.clip) {
+		return false
+	}
+	return f.matrix[0]*f.matrix[3]-f.matrix[1]*f.matrix[2] != 0
+}
+
+// reusePeriod returns the spacing, in the cached frame's pixels, that a
+// generator's pixels fall on when it's scaled by a whole factor, or a whole
+// fraction, from the cached frame
+func reusePeriod(scale float64, samples int) (float64, bool) {
+	if scale < 1 {
+		n := math.Round(1 / scale)
+		return 1 / n, samples == 1 && math.Abs(1/scale-n) < reuseTolerance
+	}
+	n := math.Round(scale)
+	return 1, (samples == 1 || n == 1) && math.Abs(scale-n) < reuseTolerance
+}
+
+// prevPixel returns where the generator's pixel at the top left of the whole
+// image falls on the cached frame, in its pixels, undoing the transform
+func (f Generator) prevPixel(prev Generator, scale float64) [2]float64 {
+	m := f.matrix
+	det := m[0]*m[3] - m[1]*m[2]
+	prevSize := pixelSize(prev)
+	x, y := (f.xPos-prev.xPos)/prevSize, (f.yPos-prev.yPos)/prevSize
+	return [2]float64{
+		(m[3]*x-m[1]*y)/det + float64(prev.width)/2 - scale*float64(f.width)/2,
+		(m[0]*y-m[2]*x)/det + float64(prev.height)/2 - scale*float64(f.height)/2,
+	}
+}
+
+// reuse copies the pixels that coincide with the cached frame's into the
+// image, and marks them known
+func (f *Generator) reuse(prev Generator, scale float64) int {
+	region := f.Img.Bounds()
+	origin := f.prevPixel(prev, scale)
+
+	// matches returns the cached frame's pixel for each of the region's, along one axis, or -1 if there isn't one
+	matches := func(origin float64, min, max, prevMin, prevMax int) []int {
+		pixels := make([]int, max-min)
+		for key := range pixels {
+			pixel := origin + scale*float64(min+key)
+			rounded := math.Round(pixel)
+			pixels[key] = -1
+			if math.Abs(pixel-rounded) < reuseTolerance && int(rounded) >= prevMin && int(rounded) < prevMax {
+				pixels[key] = int(rounded)
+			}
+		}
+		return pixels
+	}
+	prevRegion := prev.Img.Bounds()
+	columns := matches(origin[0], region.Min.X, region.Max.X, prevRegion.Min.X, prevRegion.Max.X)
+	rows := matches(origin[1], region.Min.Y, region.Max.Y, prevRegion.Min.Y, prevRegion.Max.Y)
+
+	f.known = make([]bool, region.Dx()*region.Dy())
+	reused := 0
+	for y, prevY := range rows {
+		if prevY < 0 {
+			continue
+		}
+		for x, prevX := range columns {
+			if prevX < 0 {
+				continue
+			}
+			copy(f.Img.Pix[f.Img.PixOffset(region.Min.X+x, region.Min.Y+y):][:4], prev.Img.Pix[prev.Img.PixOffset(prevX, prevY):])
+			f.known[y*region.Dx()+x] = true
+			reused++
+		}
+	}
+	return reused
+}
@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"bytes"
+	"testing"
+)
+
+// cachedFrame renders a 64x48 frame of the mandelbrot set and keeps it in a new cache
+func cachedFrame(t *testing.T, samples int) (*FrameCache, PointFunc) {
+	t.Helper()
+	pointFunc, err := GetPointFunc("mandelbrot", "smoothcolor", nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := NewGenerator(64, 48, 2, 64, samples, -0.5, 0, 0.8, pointFunc)
+	gen.Generate()
+	cache := NewFrameCache()
+	cache.Keep("mandelbrot", gen)
+	return cache, pointFunc
+}
+
+func TestFrameCacheReuse(t *testing.T) {
+	// The cached frame's pixels are 2/48/0.8 wide
+	size := 2.0 / 48 / 0.8
+	tests := []struct {
+		name   string
+		x, y   float64
+		zoom   float64
+		reused int
+	}{
+		{"pan", -0.5 + 10*size, 6 * size, 0.8, (64 - 10) * (48 - 6)},
+		{"zoom in 2x", -0.5, 0, 1.6, 32 * 24},
+		{"zoom in 4x", -0.5, 0, 3.2, 16 * 12},
+		{"zoom out 2x", -0.5, 0, 0.4, 32 * 24},
+	}
+	for _, test := range tests {
+		cache, pointFunc := cachedFrame(t, 1)
+		gen := NewGenerator(64, 48, 2, 64, 1, test.x, test.y, test.zoom, pointFunc)
+		if reused := cache.Prepare("mandelbrot", &gen); reused != test.reused {
+			t.Errorf("%s reused %d pixels, want %d", test.name, reused, test.reused)
+		}
+		gen.Generate()
+
+		// Prepare can nudge the centre, so render afresh from wherever it ends up
+		x, y, zoom, _ := gen.View()
+		fresh := NewGenerator(64, 48, 2, 64, 1, x, y, zoom, pointFunc)
+		fresh.Generate()
+		if !bytes.Equal(gen.Img.Pix, fresh.Img.Pix) {
+			t.Errorf("%s isn't the same as a fresh render", test.name)
+		}
+	}
+}
+
+func TestFrameCacheReuseNothing(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		cap     int
+		samples int
+		zoom    float64
+	}{
+		{"a different key", "julia", 64, 1, 0.8},
+		{"a different iteration cap", "mandelbrot", 128, 1, 0.8},
+		{"several samples zooming in", "mandelbrot", 64, 2, 1.6},
+		{"several samples zooming out", "mandelbrot", 64, 2, 0.4},
+	}
+	for _, test := range tests {
+		cache, pointFunc := cachedFrame(t, test.samples)
+		gen := NewGenerator(64, 48, 2, test.cap, test.samples, -0.5, 0, test.zoom, pointFunc)
+		if reused := cache.Prepare(test.key, &gen); reused != 0 {
+			t.Errorf("%s reused %d pixels, want none", test.name, reused)
+		}
+		if gen.known != nil {
+			t.Errorf("%s marked pixels known", test.name)
+		}
+	}
+
+	// Several samples are fine for panning, which doesn't move them within the pixels
+	cache, pointFunc := cachedFrame(t, 2)
+	gen := NewGenerator(64, 48, 2, 64, 2, -0.5, 0, 0.8, pointFunc)
+	if reused := cache.Prepare("mandelbrot", &gen); reused != 64*48 {
+		t.Errorf("several samples without moving reused %d pixels, want %d", reused, 64*48)
+	}
+}
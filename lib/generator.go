@@ -1,9 +1,12 @@
 package lib
 
 import (
+	"context"
 	"image"
 	"image/color"
+	"math"
 	"sync"
+	"sync/atomic"
 )
 
 // PointFunc is an integrated fractal & color function used by a generator
@@ -15,6 +18,9 @@ type Generator struct {
 	xPos         float64
 	yPos         float64
 	zoom         float64
+	rotation     float64
+	affine       [4]float64
+	matrix       [4]float64
 	scaler       float64
 	width        int
 	height       int
@@ -22,87 +28,211 @@ type Generator struct {
 	iterationCap int
 	fn           PointFunc
 	samples      int
+	expMap       bool
+	clip         *Bounds
+	done         *int64 // pixels rendered so far, shared by copies of the generator
+	known        []bool // pixels of Img a FrameCache has filled in, which aren't rendered again
 }
 
 // NewGenerator returns a generator!
 func NewGenerator(width, height, routines, iterationCap, samples int, xPos, yPos, zoom float64, fn PointFunc) Generator {
+	return NewRegionGenerator(image.Rect(0, 0, width, height), width, height, routines, iterationCap, samples, xPos, yPos, zoom, fn)
+}
 
-	// Pick the smaller of the two dimensions (width and height) and use that length in
-	// pixels as the length of 2 divided by the zoom factor as the scale for both axis.
-	var scaler float64
-	if width < height {
-		scaler = float64(width)
-	} else {
-		scaler = float64(height)
-	}
-
+// NewRegionGenerator returns a generator that renders just a region of the
+// image NewGenerator would, exactly as it would render it, so big images can
+// be rendered a piece at a time. Its image has the region's bounds, rather
+// than starting at (0, 0).
+func NewRegionGenerator(region image.Rectangle, width, height, routines, iterationCap, samples int, xPos, yPos, zoom float64, fn PointFunc) Generator {
 	return Generator{
-		image.NewNRGBA(image.Rect(0, 0, width, height)),
+		image.NewNRGBA(region.Intersect(image.Rect(0, 0, width, height))),
 		xPos,
 		yPos,
 		zoom,
-		scaler,
+		0,
+		identity,
+		identity,
+		scaler(width, height),
 		width,
 		height,
 		routines,
 		iterationCap,
 		fn,
 		samples,
+		false,
+		nil,
+		new(int64),
+		nil,
+	}
+}
+
+// Pick the smaller of the two dimensions (width and height) and use that length in
+// pixels as the length of 2 divided by the zoom factor as the scale for both axis.
+func scaler(width, height int) float64 {
+	if width < height {
+		return float64(width)
 	}
+	return float64(height)
 }
 
 // Generate spins out our workers!
 func (f Generator) Generate() {
+	f.GenerateContext(context.Background())
+}
+
+// GenerateContext is Generate, but stops early if the context is cancelled,
+// leaving the image part rendered and returning the context's error.
+func (f Generator) GenerateContext(ctx context.Context) error {
+	atomic.StoreInt64(f.done, 0)
+
 	var wg sync.WaitGroup
 	wg.Add(f.routines)
 
 	for routine := 0; routine < f.routines; routine++ {
-		go f.genRoutine(&wg, routine)
+		go f.genRoutine(ctx.Done(), &wg, routine)
 	}
 
 	wg.Wait()
+	return ctx.Err()
+}
+
+// Progress returns the fraction of the image rendered so far, from 0 to 1. It
+// can be called while the generator is generating.
+func (f Generator) Progress() float64 {
+	region := f.Img.Bounds()
+	return float64(atomic.LoadInt64(f.done)) / float64(region.Dx()*region.Dy())
+}
+
+// identity is the 2x2 identity matrix, in row-major order
+var identity = [4]float64{1, 0, 0, 1}
+
+// SetView moves the generator to a new centre, zoom and rotation (in degrees)
+// so that one generator can be reused to render many frames.
+func (f *Generator) SetView(xPos, yPos, zoom, rotation float64) {
+	f.xPos = xPos
+	f.yPos = yPos
+	f.zoom = zoom
+	f.rotation = rotation
+	f.known = nil
+	f.updateMatrix()
+}
+
+// View returns the generator's centre, zoom and rotation (in degrees), as set by SetView.
+func (f Generator) View() (xPos, yPos, zoom, rotation float64) {
+	return f.xPos, f.yPos, f.zoom, f.rotation
+}
+
+// SetTransform sets a 2x2 matrix, in row-major order, that is applied to the
+// view before it is rotated. This allows skewed and non-uniformly scaled views.
+func (f *Generator) SetTransform(a, b, c, d float64) {
+	f.affine = [4]float64{a, b, c, d}
+	f.known = nil
+	f.updateMatrix()
+}
+
+// updateMatrix combines the rotation and the affine transform into the one matrix used by pixToCoord
+func (f *Generator) updateMatrix() {
+	sin, cos := math.Sincos(f.rotation * math.Pi / 180)
+	a := f.affine
+	f.matrix = [4]float64{
+		cos*a[0] - sin*a[2], cos*a[1] - sin*a[3],
+		sin*a[0] + cos*a[2], sin*a[1] + cos*a[3],
+	}
+}
+
+// SetPointFunc swaps out the point function the generator renders.
+func (f *Generator) SetPointFunc(fn PointFunc) {
+	f.fn = fn
+	f.known = nil
 }
 
 func (f Generator) pixToCoord(xPix, yPix float64) (xCoord, yCoord float64) {
-	xCoord = ((xPix - (float64(f.width) / 2)) * ((2 / f.scaler) / f.zoom)) + f.xPos
-	yCoord = ((yPix - (float64(f.height) / 2)) * ((2 / f.scaler) / f.zoom)) + f.yPos
+	if f.expMap {
+		return f.expMapToCoord(xPix, yPix)
+	}
+
+	xOffset := (xPix - (float64(f.width) / 2)) * ((2 / f.scaler) / f.zoom)
+	yOffset := (yPix - (float64(f.height) / 2)) * ((2 / f.scaler) / f.zoom)
+	xCoord = (xOffset * f.matrix[0]) + (yOffset * f.matrix[1]) + f.xPos
+	yCoord = (xOffset * f.matrix[2]) + (yOffset * f.matrix[3]) + f.yPos
 	return xCoord, yCoord
 }
 
-func (f Generator) genRoutine(wg *sync.WaitGroup, rno int) {
+func (f Generator) genRoutine(stop <-chan struct{}, wg *sync.WaitGroup, rno int) {
+	defer wg.Done()
+
+	px := f.newPixelRenderer()
+	routines := f.routines
+	region := f.Img.Bounds()
+	regionWidth := region.Dx()
+	size := regionWidth * region.Dy()
+
+	/* Progress is counted, and cancellation checked for, a row's worth of
+	   pixels at a time, to keep the routines from contending.
+	*/
+	done := int64(0)
 
+	for i := rno; i < size; i = i + routines {
+		if done++; done == int64(regionWidth) {
+			atomic.AddInt64(f.done, done)
+			done = 0
+
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+
+		if f.known == nil || !f.known[i] {
+			px.render(region.Min.X+i%regionWidth, region.Min.Y+i/regionWidth)
+		}
+	}
+
+	atomic.AddInt64(f.done, done)
+}
+
+// pixelRenderer renders pixels of a generator's image, with the sample
+// offsets worked out once rather than for every pixel
+type pixelRenderer struct {
+	f              Generator
+	offsets        []float64
+	samplesSquared float64
+}
+
+func (f Generator) newPixelRenderer() pixelRenderer {
 	// Keeping as many recalculated values outside of the for loops as possible.
 	offsets := make([]float64, f.samples)
 	for sample := 0; sample < f.samples; sample++ {
 		offsets[sample] = (1 + float64(2*sample) - float64(f.samples)) / float64(2*(f.samples))
 	}
-	samplesSquared := float64(f.samples * f.samples)
-	routines := f.routines
-	size := f.width * f.height
+	return pixelRenderer{f, offsets, float64(f.samples * f.samples)}
+}
 
-	for i := rno; i < size; i = i + routines {
-		xPix := i % f.width
-		yPix := i / f.width
+// render renders a pixel, with all of its samples
+func (px pixelRenderer) render(xPix, yPix int) {
+	f := px.f
+	if f.clip != nil && !f.clip.Contains(f.pixToCoord(float64(xPix), float64(yPix))) {
+		f.Img.Set(xPix, yPix, color.RGBA{})
+		return
+	}
 
-		R, G, B, A := 0.0, 0.0, 0.0, 0.0
+	R, G, B, A := 0.0, 0.0, 0.0, 0.0
 
-		for xSample := 0; xSample < f.samples; xSample++ {
-			for ySample := 0; ySample < f.samples; ySample++ {
-				xCoord, yCoord := f.pixToCoord(float64(xPix)+offsets[xSample], float64(yPix)+offsets[ySample])
+	for xSample := 0; xSample < f.samples; xSample++ {
+		for ySample := 0; ySample < f.samples; ySample++ {
+			xCoord, yCoord := f.pixToCoord(float64(xPix)+px.offsets[xSample], float64(yPix)+px.offsets[ySample])
 
-				r, g, b, a := f.fn(xCoord, yCoord, f.iterationCap)
+			r, g, b, a := f.fn(xCoord, yCoord, f.iterationCap)
 
-				R, G, B, A = R+r, G+g, B+b, A+a
-			}
+			R, G, B, A = R+r, G+g, B+b, A+a
 		}
-
-		f.Img.Set(xPix, yPix,
-			color.RGBA{
-				uint8(R / samplesSquared),
-				uint8(G / samplesSquared),
-				uint8(B / samplesSquared),
-				uint8(A / samplesSquared)})
 	}
 
-	wg.Done()
+	f.Img.Set(xPix, yPix,
+		color.RGBA{
+			uint8(R / px.samplesSquared),
+			uint8(G / px.samplesSquared),
+			uint8(B / px.samplesSquared),
+			uint8(A / px.samplesSquared)})
 }
@@ -0,0 +1,261 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// An iterated function system is a set of maps of the plane, each shrinking
+// it onto part of itself. Picking one of the maps at random over and over
+// again and applying it to a point, the chaos game, scatters the point across
+// the system's attractor. Fractal flames are the same, with each map's affine
+// part followed by a weighted sum of non-linear variations.
+
+// Named errors for iterated function systems
+var (
+	ErrInvalidIFS       = errors.New("invalid iterated function system name")
+	ErrInvalidVariation = errors.New("invalid variation name")
+	ErrNoTransforms     = errors.New("an iterated function system needs at least one transform")
+	ErrInvalidWeights   = errors.New("transform weights can't be negative")
+	ErrNoAttractor      = errors.New("every point escapes to infinity")
+)
+
+// Transform is one of the maps of an iterated function system. Coefs are the
+// affine map (x, y) -> (a*x + b*y + e, c*x + d*y + f) as [a, b, c, d, e, f].
+// Weight is how likely the map is to be picked, and Color is where on the
+// palette, from 0 to 1, points it maps are pulled towards. Variations are the
+// weights of the variations applied after the affine map, which is linear
+// alone if there are none.
+type Transform struct {
+	Coefs      [6]float64         `json:"coefs"`
+	Weight     float64            `json:"weight"`
+	Color      float64            `json:"color"`
+	Variations map[string]float64 `json:"variations"`
+}
+
+// IFS is an iterated function system, or a fractal flame. If none of the
+// transforms have a weight they are equally likely, and if none have a color
+// they are spread evenly across the palette.
+type IFS struct {
+	Transforms []Transform `json:"transforms"`
+}
+
+// Variation is a non-linear map of the plane used by fractal flames
+type Variation func(x, y float64) (float64, float64)
+
+// Variations is a map of the available variations by name, from Draves and
+// Reckase's "The Fractal Flame Algorithm"
+var Variations = map[string]Variation{
+	"linear": func(x, y float64) (float64, float64) {
+		return x, y
+	},
+	"sinusoidal": func(x, y float64) (float64, float64) {
+		return math.Sin(x), math.Sin(y)
+	},
+	"spherical": func(x, y float64) (float64, float64) {
+		r2 := x*x + y*y
+		return x / r2, y / r2
+	},
+	"swirl": func(x, y float64) (float64, float64) {
+		sin, cos := math.Sincos(x*x + y*y)
+		return x*sin - y*cos, x*cos + y*sin
+	},
+	"horseshoe": func(x, y float64) (float64, float64) {
+		r := math.Hypot(x, y)
+		return (x - y) * (x + y) / r, 2 * x * y / r
+	},
+	"polar": func(x, y float64) (float64, float64) {
+		return math.Atan2(x, y) / math.Pi, math.Hypot(x, y) - 1
+	},
+	"handkerchief": func(x, y float64) (float64, float64) {
+		r, theta := math.Hypot(x, y), math.Atan2(x, y)
+		return r * math.Sin(theta+r), r * math.Cos(theta-r)
+	},
+	"heart": func(x, y float64) (float64, float64) {
+		r, theta := math.Hypot(x, y), math.Atan2(x, y)
+		sin, cos := math.Sincos(theta * r)
+		return r * sin, -r * cos
+	},
+	"disc": func(x, y float64) (float64, float64) {
+		r, theta := math.Hypot(x, y), math.Atan2(x, y)
+		sin, cos := math.Sincos(math.Pi * r)
+		return theta / math.Pi * sin, theta / math.Pi * cos
+	},
+	"spiral": func(x, y float64) (float64, float64) {
+		r, theta := math.Hypot(x, y), math.Atan2(x, y)
+		return (math.Cos(theta) + math.Sin(r)) / r, (math.Sin(theta) - math.Cos(r)) / r
+	},
+	"hyperbolic": func(x, y float64) (float64, float64) {
+		r, theta := math.Hypot(x, y), math.Atan2(x, y)
+		return math.Sin(theta) / r, r * math.Cos(theta)
+	},
+	"diamond": func(x, y float64) (float64, float64) {
+		r, theta := math.Hypot(x, y), math.Atan2(x, y)
+		return math.Sin(theta) * math.Cos(r), math.Cos(theta) * math.Sin(r)
+	},
+	"fisheye": func(x, y float64) (float64, float64) {
+		scale := 2 / (math.Hypot(x, y) + 1)
+		return scale * y, scale * x
+	},
+	"exponential": func(x, y float64) (float64, float64) {
+		scale := math.Exp(x - 1)
+		sin, cos := math.Sincos(math.Pi * y)
+		return scale * cos, scale * sin
+	},
+	"bubble": func(x, y float64) (float64, float64) {
+		scale := 4 / (x*x + y*y + 4)
+		return scale * x, scale * y
+	},
+	"cylinder": func(x, y float64) (float64, float64) {
+		return math.Sin(x), y
+	},
+}
+
+// IFSs is a map of the built in iterated function systems and flames by name
+var IFSs = map[string]IFS{
+	"fern": {[]Transform{
+		{Coefs: [6]float64{0, 0, 0, 0.16, 0, 0}, Weight: 0.01, Color: 0},
+		{Coefs: [6]float64{0.85, 0.04, -0.04, 0.85, 0, 1.6}, Weight: 0.85, Color: 0.3},
+		{Coefs: [6]float64{0.2, -0.26, 0.23, 0.22, 0, 1.6}, Weight: 0.07, Color: 0.2},
+		{Coefs: [6]float64{-0.15, 0.28, 0.26, 0.24, 0, 0.44}, Weight: 0.07, Color: 0.4},
+	}},
+	"sierpinski": {[]Transform{
+		{Coefs: [6]float64{0.5, 0, 0, 0.5, 0, 0}},
+		{Coefs: [6]float64{0.5, 0, 0, 0.5, 0.5, 0}},
+		{Coefs: [6]float64{0.5, 0, 0, 0.5, 0.25, 0.433}},
+	}},
+	"carpet": {[]Transform{
+		{Coefs: [6]float64{1.0 / 3, 0, 0, 1.0 / 3, 0, 0}},
+		{Coefs: [6]float64{1.0 / 3, 0, 0, 1.0 / 3, 1.0 / 3, 0}},
+		{Coefs: [6]float64{1.0 / 3, 0, 0, 1.0 / 3, 2.0 / 3, 0}},
+		{Coefs: [6]float64{1.0 / 3, 0, 0, 1.0 / 3, 0, 1.0 / 3}},
+		{Coefs: [6]float64{1.0 / 3, 0, 0, 1.0 / 3, 2.0 / 3, 1.0 / 3}},
+		{Coefs: [6]float64{1.0 / 3, 0, 0, 1.0 / 3, 0, 2.0 / 3}},
+		{Coefs: [6]float64{1.0 / 3, 0, 0, 1.0 / 3, 1.0 / 3, 2.0 / 3}},
+		{Coefs: [6]float64{1.0 / 3, 0, 0, 1.0 / 3, 2.0 / 3, 2.0 / 3}},
+	}},
+	"dragon": {[]Transform{
+		{Coefs: [6]float64{0.5, -0.5, 0.5, 0.5, 0, 0}},
+		{Coefs: [6]float64{-0.5, -0.5, 0.5, -0.5, 1, 0}},
+	}},
+	"levy": {[]Transform{
+		{Coefs: [6]float64{0.5, -0.5, 0.5, 0.5, 0, 0}},
+		{Coefs: [6]float64{0.5, 0.5, -0.5, 0.5, 0.5, 0.5}},
+	}},
+	"swirls": {[]Transform{
+		{Coefs: [6]float64{0.6, -0.4, 0.4, 0.6, 0.2, 0}, Weight: 1, Color: 0, Variations: map[string]float64{"swirl": 1}},
+		{Coefs: [6]float64{0.5, 0, 0, 0.5, -0.5, 0.3}, Weight: 1, Color: 0.5, Variations: map[string]float64{"spherical": 0.6, "linear": 0.4}},
+		{Coefs: [6]float64{-0.3, 0.5, -0.5, -0.3, 0, -0.4}, Weight: 0.5, Color: 1, Variations: map[string]float64{"sinusoidal": 1}},
+	}},
+	"bubbles": {[]Transform{
+		{Coefs: [6]float64{0.7, 0.2, -0.2, 0.7, 0.3, 0.1}, Weight: 1, Color: 0.1, Variations: map[string]float64{"spherical": 1}},
+		{Coefs: [6]float64{0.4, -0.6, 0.6, 0.4, -0.4, 0.2}, Weight: 1, Color: 0.6, Variations: map[string]float64{"bubble": 1}},
+		{Coefs: [6]float64{0.5, 0, 0, 0.5, 0, -0.6}, Weight: 0.7, Color: 0.9, Variations: map[string]float64{"linear": 0.5, "heart": 0.5}},
+	}},
+}
+
+// GetIFS returns a built in iterated function system if the name is valid
+func GetIFS(name string) (IFS, error) {
+	ifs, validIFS := IFSs[strings.ToLower(name)]
+	if !validIFS {
+		return IFS{}, ErrInvalidIFS
+	}
+	return ifs, nil
+}
+
+// weightedVariation is a variation and its weight in a transform
+type weightedVariation struct {
+	weight float64
+	fn     Variation
+}
+
+// chaosTransform is a transform ready for the chaos game
+type chaosTransform struct {
+	coefs      [6]float64
+	cumulative float64
+	color      float64
+	variations []weightedVariation
+}
+
+// compile checks the system is valid and readies its transforms for the chaos
+// game, with cumulative weights so they can be picked by a single random number
+func (ifs IFS) compile() ([]chaosTransform, error) {
+	if len(ifs.Transforms) == 0 {
+		return nil, ErrNoTransforms
+	}
+
+	total, colored := 0.0, false
+	for _, t := range ifs.Transforms {
+		if t.Weight < 0 {
+			return nil, ErrInvalidWeights
+		}
+		total += t.Weight
+		colored = colored || t.Color != 0
+	}
+
+	transforms := make([]chaosTransform, len(ifs.Transforms))
+	cumulative := 0.0
+	for key, t := range ifs.Transforms {
+		weight := t.Weight / total
+		if total == 0 {
+			weight = 1 / float64(len(ifs.Transforms))
+		}
+		cumulative += weight
+
+		color := t.Color
+		if !colored && len(ifs.Transforms) > 1 {
+			color = float64(key) / float64(len(ifs.Transforms)-1)
+		}
+
+		/* Variations are summed in order of name, as map order is random
+		   and floating point addition depends upon the order.
+		*/
+		names := make([]string, 0, len(t.Variations))
+		for name := range t.Variations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		variations := []weightedVariation{}
+		for _, name := range names {
+			fn, exists := Variations[strings.ToLower(name)]
+			if !exists {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidVariation, name)
+			}
+			variations = append(variations, weightedVariation{t.Variations[name], fn})
+		}
+
+		transforms[key] = chaosTransform{t.Coefs, cumulative, color, variations}
+	}
+	transforms[len(transforms)-1].cumulative = 1
+	return transforms, nil
+}
+
+// pick picks a transform at random by weight
+func pick(transforms []chaosTransform, rng *rand.Rand) chaosTransform {
+	r := rng.Float64()
+	for _, t := range transforms {
+		if r < t.cumulative {
+			return t
+		}
+	}
+	return transforms[len(transforms)-1]
+}
+
+// apply maps a point through the transform
+func (t chaosTransform) apply(x, y float64) (float64, float64) {
+	x, y = t.coefs[0]*x+t.coefs[1]*y+t.coefs[4], t.coefs[2]*x+t.coefs[3]*y+t.coefs[5]
+	if len(t.variations) == 0 {
+		return x, y
+	}
+	var vx, vy float64
+	for _, v := range t.variations {
+		dx, dy := v.fn(x, y)
+		vx += v.weight * dx
+		vy += v.weight * dy
+	}
+	return vx, vy
+}
@@ -0,0 +1,56 @@
+package lib
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// ErrNoJulia is returned for fractals without a julia counterpart
+var ErrNoJulia = errors.New("fractal has no julia counterpart")
+
+// JuliaConstants returns the name of the fractal's julia counterpart, and its
+// constants for the point (re, im) of the fractal's parameter plane.
+func JuliaConstants(fractalName string, constants []float64, re, im float64) (string, []float64, error) {
+	frac, err := GetFractal(fractalName)
+	if err != nil {
+		return "", nil, err
+	}
+	if frac.Julia == "" {
+		return "", nil, ErrNoJulia
+	}
+	return frac.Julia, append([]float64{re, im}, constants...), nil
+}
+
+// JuliaAtlas fills the generator's image with a cells by cells grid of julia
+// sets in lieu of the fractal itself. Each julia set is for the point of the
+// generator's view at the centre of its cell, and is centred on 0 with the
+// given zoom factor.
+func JuliaAtlas(gen Generator, cells int, zoom float64, fractalName, colorName string, constants []float64, opts Options) error {
+	for row := 0; row < cells; row++ {
+		for col := 0; col < cells; col++ {
+			cell := image.Rect(
+				col*gen.width/cells, row*gen.height/cells,
+				(col+1)*gen.width/cells, (row+1)*gen.height/cells,
+			)
+			if cell.Empty() {
+				continue
+			}
+
+			re, im := gen.pixToCoord(float64(cell.Min.X+cell.Max.X)/2-0.5, float64(cell.Min.Y+cell.Max.Y)/2-0.5)
+			juliaName, juliaConstants, err := JuliaConstants(fractalName, constants, re, im)
+			if err != nil {
+				return err
+			}
+			fn, err := GetPointFunc(juliaName, colorName, juliaConstants, opts)
+			if err != nil {
+				return err
+			}
+
+			julia := NewGenerator(cell.Dx(), cell.Dy(), gen.routines, gen.iterationCap, gen.samples, 0, 0, zoom, fn)
+			julia.Generate()
+			draw.Draw(gen.Img, cell, julia.Img, image.Point{}, draw.Src)
+		}
+	}
+	return nil
+}
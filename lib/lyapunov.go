@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// The lyapunov fractal is of the logistic map x -> r*x*(1 - x), a real map,
+// with r switching between two values, a and b, in a repeating sequence. The
+// lyapunov exponent measures how quickly nearby values of x drift apart:
+// negative where x settles into a cycle, positive where it's chaotic.
+
+// lyapunovWarmup is the fraction of the iterations used to let x settle
+// before the exponent is measured
+const lyapunovWarmup = 0.25
+
+// parseSequence turns a sequence of As and Bs into a sequence of bools, true for b
+func parseSequence(sequence string) []bool {
+	sequence = strings.ToUpper(sequence)
+	useB := make([]bool, len(sequence))
+	for key, char := range sequence {
+		useB[key] = char == 'B'
+	}
+	return useB
+}
+
+// checkSequence checks the sequence is made up of As and Bs only
+func checkSequence(params Params) error {
+	sequence := params.String("sequence")
+	if len(sequence) == 0 || strings.Trim(strings.ToUpper(sequence), "AB") != "" {
+		return errors.New("the sequence must be made up of As and Bs, e.g. AB")
+	}
+	return nil
+}
+
+// lyapunov returns a PointFunc for the lyapunov fractal with the given sequence
+func lyapunov(color ColorFunc, sequence []bool) PointFunc {
+	return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+		// b goes up the y axis like -y, which is the opposite way to yCoord
+		a, b := xCoord, -yCoord
+		warmup := int(float64(iterationCap) * lyapunovWarmup)
+
+		x := 0.5
+		sum := 0.0
+		measured := 0
+		for iteration := 0; iteration < iterationCap; iteration++ {
+			r := a
+			if sequence[iteration%len(sequence)] {
+				r = b
+			}
+			x = r * x * (1 - x)
+			if iteration >= warmup {
+				// The derivative of the map is r*(1 - 2x)
+				if derivative := math.Abs(r * (1 - 2*x)); derivative > 0 {
+					sum += math.Log(derivative)
+				}
+				measured++
+			}
+		}
+
+		exponent := 0.0
+		if measured > 0 {
+			exponent = sum / float64(measured)
+		}
+		if math.IsNaN(exponent) || math.IsInf(x, 0) {
+			exponent = math.Inf(1)
+		}
+
+		return color(
+			iterationCap,
+			iterationCap,
+			map[string]interface{}{
+				"exponent": exponent,
+			},
+		)
+	}
+}
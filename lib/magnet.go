@@ -0,0 +1,61 @@
+package lib
+
+// The magnet fractals come from models of magnetism on a lattice. As well as
+// escaping to infinity, points can converge to z = 1, so iterating stops at
+// whichever happens first and both regions are colored by iteration count.
+
+// magnetTolerance is how close to 1 z must get for it to have converged
+const magnetTolerance = 1e-6
+
+var one = Complex{1, 0}
+
+// magnetI is the type I magnet map, ((z^2 + c - 1) / (2z + c - 2))^2
+func magnetI(z, c Complex) Complex {
+	return z.Sq().Add(c).Sub(one).Div(z.Add(z).Add(c).Sub(Complex{2, 0})).Sq()
+}
+
+// magnetII is the type II magnet map,
+// ((z^3 + 3(c-1)z + (c-1)(c-2)) / (3z^2 + 3(c-2)z + (c-1)(c-2) + 1))^2
+func magnetII(z, c Complex) Complex {
+	three := Complex{3, 0}
+	cm1, cm2 := c.Sub(one), c.Sub(Complex{2, 0})
+	numerator := z.Sq().Mul(z).Add(three.Mul(cm1).Mul(z)).Add(cm1.Mul(cm2))
+	denominator := three.Mul(z.Sq()).Add(three.Mul(cm2).Mul(z)).Add(cm1.Mul(cm2)).Add(one)
+	return numerator.Div(denominator).Sq()
+}
+
+// magnet returns a PointFunc for a magnet map. If julia is nil c is the point
+// being rendered and z starts at 0, otherwise c is *julia and z starts at the
+// point being rendered.
+func magnet(color ColorFunc, bailout Bailout, fn func(z, c Complex) Complex, julia *Complex) PointFunc {
+	return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+		c, z := Complex{xCoord, yCoord}, Complex{0, 0}
+		if julia != nil {
+			c, z = *julia, Complex{xCoord, yCoord}
+		}
+		iterations := 0
+		converged := false
+
+		for iterations = 0; !converged && !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
+			z = fn(z, c)
+			converged = z.Sub(one).Abs() < magnetTolerance
+		}
+
+		// 1 is the only root, so the root color schemes color converging points
+		root := -1
+		if converged {
+			root = 0
+		}
+
+		return color(
+			iterations,
+			iterationCap,
+			map[string]interface{}{
+				"z":       z,
+				"bailout": bailout,
+				"root":    root,
+				"roots":   1,
+			},
+		)
+	}
+}
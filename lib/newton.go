@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"errors"
+	"math"
+)
+
+// Newton's method fractals don't escape, they converge: each point is
+// iterated until z stops moving, and colored by which root of the
+// polynomial it ends up at.
+
+// newtonTolerance is how little z must move in an iteration for it to have
+// converged, and how close to a root it must then be to count as that root.
+const newtonTolerance = 1e-6
+
+// newton returns a PointFunc for newton's method on p, which has the given roots.
+// relaxation scales each step, with 1 being plain newton's method.
+func newton(color ColorFunc, p polynomial, roots []Complex, relaxation Complex) PointFunc {
+	return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+		z := Complex{xCoord, yCoord}
+		iterations := 0
+		converged := false
+
+		for iterations = 0; !converged && iterations < iterationCap; iterations++ {
+			value, derivative := p.eval(z)
+			step := relaxation.Mul(value.Div(derivative))
+			z = z.Sub(step)
+			converged = step.Abs() < newtonTolerance
+		}
+
+		root := -1
+		if converged {
+			root = nearestRoot(z, roots, math.Sqrt(newtonTolerance))
+		}
+
+		return color(
+			iterations,
+			iterationCap,
+			map[string]interface{}{
+				"z":     z,
+				"root":  root,
+				"roots": len(roots),
+			},
+		)
+	}
+}
+
+// nova returns a PointFunc for the nova fractal, newton's method for z^n - 1
+// with c added each step. If julia is nil c is the point being rendered and z
+// starts at 1, otherwise c is *julia and z starts at the point being rendered.
+func nova(color ColorFunc, bailout Bailout, n int, relaxation Complex, julia *Complex) PointFunc {
+	p, _ := unityPolynomial(n)
+	return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+		c, z := Complex{xCoord, yCoord}, Complex{1, 0}
+		if julia != nil {
+			c, z = *julia, Complex{xCoord, yCoord}
+		}
+		iterations := 0
+		converged := false
+
+		for iterations = 0; !converged && iterations < iterationCap; iterations++ {
+			value, derivative := p.eval(z)
+			next := z.Sub(relaxation.Mul(value.Div(derivative))).Add(c)
+			converged = next.Sub(z).Abs() < newtonTolerance
+			z = next
+		}
+
+		return color(
+			iterations,
+			iterationCap,
+			map[string]interface{}{
+				"z":       z,
+				"bailout": bailout,
+			},
+		)
+	}
+}
+
+// unityPolynomial returns z^n - 1, and its roots: the nth roots of unity
+func unityPolynomial(n int) (polynomial, []Complex) {
+	p := make(polynomial, n+1)
+	p[0] = Complex{1, 0}
+	p[n] = Complex{-1, 0}
+
+	roots := make([]Complex, n)
+	for key := range roots {
+		theta := 2 * math.Pi * float64(key) / float64(n)
+		roots[key] = Complex{math.Cos(theta), math.Sin(theta)}
+	}
+	return p, roots
+}
+
+// The parameters of the newton fractals
+var (
+	newtonN            = Param{Name: "n", Type: ParamInt, Default: "3", Min: 2, Max: 64, Help: "the power of z^n - 1"}
+	newtonCoefficients = Param{Name: "coefficients", Type: ParamFloats, Default: "1,0,0,-1", Min: 2, Max: 64, Help: "the polynomial's coefficients, highest power first"}
+	newtonRoots        = Param{Name: "roots", Type: ParamComplexes, Default: "1,-0.5+0.866i,-0.5-0.866i", Min: 1, Max: 64, Help: "the polynomial's roots"}
+)
+
+// checkCoefficients checks the coefficients are of a polynomial of degree 1 or more
+func checkCoefficients(params Params) error {
+	coefficients := params.Floats("coefficients")
+	p := make(polynomial, len(coefficients))
+	for key, coeff := range coefficients {
+		p[key] = Complex{coeff, 0}
+	}
+	if p.degree() < 1 {
+		return errors.New("the polynomial must be of degree 1 or more")
+	}
+	return nil
+}
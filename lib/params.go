@@ -0,0 +1,360 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidParam is wrapped by every error from working out a fractal's parameters
+var ErrInvalidParam = errors.New("invalid parameter")
+
+// ParamType is the type of a fractal parameter's value
+type ParamType int
+
+// The types of parameter
+const (
+	ParamFloat     ParamType = iota // float64
+	ParamInt                        // int
+	ParamComplex                    // complex, e.g. -0.2+0.65i
+	ParamString                     // string
+	ParamEnum                       // string, one of the param's Options
+	ParamFloats                     // []float64, e.g. 1,0,0,-1
+	ParamComplexes                  // []complex, e.g. 1,-0.5+0.866i,-0.5-0.866i
+)
+
+var paramTypeNames = map[ParamType]string{
+	ParamFloat:     "float",
+	ParamInt:       "int",
+	ParamComplex:   "complex",
+	ParamString:    "string",
+	ParamEnum:      "enum",
+	ParamFloats:    "floats",
+	ParamComplexes: "complexes",
+}
+
+func (t ParamType) String() string {
+	return paramTypeNames[t]
+}
+
+// Param describes one of a fractal's parameters
+type Param struct {
+	Name string
+	Type ParamType
+	// Default is the value used when none is given, written the same way as
+	// a value given to -p. If it's empty the parameter must be given.
+	Default string
+	// Min and Max bound float and int values, and the length of lists, if Min < Max.
+	Min, Max float64
+	// Options are the values an enum can take.
+	Options []string
+	Help    string
+}
+
+// String outputs the parameter for the help screen
+func (p Param) String() string {
+	str := fmt.Sprintf("%s (%s", p.Name, p.Type)
+	if p.Type == ParamEnum {
+		str += ": " + strings.Join(p.Options, ", ")
+	}
+	if p.Min < p.Max {
+		str += fmt.Sprintf(", %g to %g", p.Min, p.Max)
+		if p.Type == ParamFloats || p.Type == ParamComplexes {
+			str += " values"
+		}
+	}
+	if p.Default != "" {
+		str += ", default " + p.Default
+	}
+	return str + "): " + p.Help
+}
+
+// Params are the values of a fractal's parameters by name. Fractals can
+// assume every one of their parameters is there, with a valid value.
+type Params map[string]interface{}
+
+// Float returns the value of a float parameter
+func (p Params) Float(name string) float64 {
+	return p[name].(float64)
+}
+
+// Int returns the value of an int parameter
+func (p Params) Int(name string) int {
+	return p[name].(int)
+}
+
+// Complex returns the value of a complex parameter
+func (p Params) Complex(name string) Complex {
+	return p[name].(Complex)
+}
+
+// String returns the value of a string or enum parameter
+func (p Params) String(name string) string {
+	return p[name].(string)
+}
+
+// Floats returns the value of a floats parameter
+func (p Params) Floats(name string) []float64 {
+	return p[name].([]float64)
+}
+
+// Complexes returns the value of a complexes parameter
+func (p Params) Complexes(name string) []Complex {
+	return p[name].([]Complex)
+}
+
+// params works out the values of the fractal's parameters. The constants are
+// taken by the numeric parameters in order, with a complex taking two and a
+// list taking the rest, and the strings by the string and enum parameters in
+// order. Named values, from -p, take precedence over both.
+func (frac *Fractal) params(constants []float64, strs []string, named map[string]string) (Params, error) {
+	values := Params{}
+
+	// Positional constants
+	remaining := constants
+	for _, param := range frac.Params {
+		if len(remaining) == 0 {
+			break
+		}
+		switch param.Type {
+		case ParamFloat:
+			values[param.Name], remaining = remaining[0], remaining[1:]
+		case ParamInt:
+			if remaining[0] != math.Trunc(remaining[0]) {
+				return nil, paramError(param, "must be a whole number")
+			}
+			values[param.Name], remaining = int(remaining[0]), remaining[1:]
+		case ParamComplex:
+			if len(remaining) < 2 {
+				return nil, paramError(param, "needs two constants, its real and imaginary components")
+			}
+			values[param.Name], remaining = Complex{remaining[0], remaining[1]}, remaining[2:]
+		case ParamFloats:
+			values[param.Name], remaining = append([]float64{}, remaining...), nil
+		case ParamComplexes:
+			if len(remaining)%2 != 0 {
+				return nil, paramError(param, "needs pairs of constants, the real and imaginary components of each")
+			}
+			list := make([]Complex, len(remaining)/2)
+			for key := range list {
+				list[key] = Complex{remaining[2*key], remaining[2*key+1]}
+			}
+			values[param.Name], remaining = list, nil
+		}
+	}
+	if len(remaining) > 0 {
+		return nil, fmt.Errorf("%w: too many constants, the constants are %s", ErrInvalidParam, frac.positional(ParamFloat, ParamInt, ParamComplex, ParamFloats, ParamComplexes))
+	}
+
+	// Positional strings
+	remainingStrs := strs
+	for _, param := range frac.Params {
+		if len(remainingStrs) == 0 {
+			break
+		}
+		if param.Type == ParamString || param.Type == ParamEnum {
+			value, err := param.parse(remainingStrs[0])
+			if err != nil {
+				return nil, err
+			}
+			values[param.Name], remainingStrs = value, remainingStrs[1:]
+		}
+	}
+	if len(remainingStrs) > 0 {
+		return nil, fmt.Errorf("%w: too many string constants, the string constants are %s", ErrInvalidParam, frac.positional(ParamString, ParamEnum))
+	}
+
+	// Named values
+	for name, str := range named {
+		param, exists := frac.param(name)
+		if !exists {
+			return nil, fmt.Errorf("%w: unknown parameter %q, the parameters are %s", ErrInvalidParam, name, frac.positional())
+		}
+		value, err := param.parse(str)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+
+	// Defaults, and checking the values are in range
+	for _, param := range frac.Params {
+		if _, given := values[param.Name]; !given {
+			if param.Default == "" {
+				return nil, paramError(param, "must be given")
+			}
+			value, err := param.parse(param.Default)
+			if err != nil {
+				return nil, err
+			}
+			values[param.Name] = value
+		}
+		if err := param.check(values[param.Name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if frac.Check != nil {
+		if err := frac.Check(values); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidParam, err)
+		}
+	}
+	return values, nil
+}
+
+// param finds one of the fractal's parameters by name
+func (frac *Fractal) param(name string) (Param, bool) {
+	for _, param := range frac.Params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return Param{}, false
+}
+
+// positional lists the names of the fractal's parameters of the given types, or all of them
+func (frac *Fractal) positional(types ...ParamType) string {
+	names := []string{}
+	for _, param := range frac.Params {
+		matches := len(types) == 0
+		for _, t := range types {
+			matches = matches || param.Type == t
+		}
+		if !matches {
+			continue
+		}
+		if param.Type == ParamComplex {
+			names = append(names, param.Name+" (as 2)")
+		} else {
+			names = append(names, param.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// parse parses a value for the parameter, written as it would be given to -p
+func (p Param) parse(str string) (interface{}, error) {
+	str = strings.TrimSpace(str)
+	switch p.Type {
+	case ParamFloat:
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, paramError(p, fmt.Sprintf("%q is not a number", str))
+		}
+		return value, nil
+	case ParamInt:
+		value, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, paramError(p, fmt.Sprintf("%q is not a whole number", str))
+		}
+		return value, nil
+	case ParamComplex:
+		value, err := parseComplex(str)
+		if err != nil {
+			return nil, paramError(p, fmt.Sprintf("%q is not a complex number, e.g. -0.2+0.65i", str))
+		}
+		return value, nil
+	case ParamEnum:
+		str = strings.ToLower(str)
+		for _, option := range p.Options {
+			if str == option {
+				return str, nil
+			}
+		}
+		return nil, paramError(p, fmt.Sprintf("%q is not one of %s", str, strings.Join(p.Options, ", ")))
+	case ParamFloats:
+		list := []float64{}
+		for _, item := range strings.Split(str, ",") {
+			value, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+			if err != nil {
+				return nil, paramError(p, fmt.Sprintf("%q is not a number", item))
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case ParamComplexes:
+		list := []Complex{}
+		for _, item := range strings.Split(str, ",") {
+			value, err := parseComplex(item)
+			if err != nil {
+				return nil, paramError(p, fmt.Sprintf("%q is not a complex number, e.g. -0.2+0.65i", item))
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+	return str, nil
+}
+
+// check checks a value is in the parameter's range
+func (p Param) check(value interface{}) error {
+	if !(p.Min < p.Max) {
+		return nil
+	}
+	var size float64
+	switch value := value.(type) {
+	case float64:
+		size = value
+	case int:
+		size = float64(value)
+	case []float64:
+		size = float64(len(value))
+	case []Complex:
+		size = float64(len(value))
+	default:
+		return nil
+	}
+	if size < p.Min || size > p.Max {
+		switch p.Type {
+		case ParamFloats, ParamComplexes:
+			return paramError(p, fmt.Sprintf("must have from %g to %g values", p.Min, p.Max))
+		}
+		return paramError(p, fmt.Sprintf("must be from %g to %g", p.Min, p.Max))
+	}
+	return nil
+}
+
+func paramError(p Param, problem string) error {
+	return fmt.Errorf("%w: %s %s", ErrInvalidParam, p.Name, problem)
+}
+
+// parseComplex parses complex numbers written as e.g. -0.2+0.65i, 0.65i or -1
+func parseComplex(str string) (Complex, error) {
+	str = strings.Replace(strings.TrimSpace(str), " ", "", -1)
+	if !strings.HasSuffix(str, "i") {
+		real, err := strconv.ParseFloat(str, 64)
+		return Complex{real, 0}, err
+	}
+	str = strings.TrimSuffix(str, "i")
+
+	// Split at the last sign that isn't the first character or part of an exponent
+	split := 0
+	for key := len(str) - 1; key > 0; key-- {
+		if (str[key] == '+' || str[key] == '-') && str[key-1] != 'e' && str[key-1] != 'E' {
+			split = key
+			break
+		}
+	}
+
+	real := 0.0
+	if split > 0 {
+		var err error
+		if real, err = strconv.ParseFloat(str[:split], 64); err != nil {
+			return Complex{}, err
+		}
+	}
+	imagStr := str[split:]
+	switch imagStr {
+	case "", "+":
+		imagStr = "1"
+	case "-":
+		imagStr = "-1"
+	}
+	imag, err := strconv.ParseFloat(imagStr, 64)
+	return Complex{real, imag}, err
+}
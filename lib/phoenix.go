@@ -0,0 +1,46 @@
+package lib
+
+// The phoenix fractal iterates
+//
+//	z -> z^2 + c + p*y, y -> z
+//
+// so each value of z depends upon the one before it too. The iterator passed
+// to the color funcs carries y along with it, so it can carry on from where
+// the escape loop stopped.
+
+// phoenixP is the p parameter of the phoenix fractals
+var phoenixP = Param{Name: "p", Type: ParamComplex, Default: "-0.5", Help: "p, the weight of the previous value of z"}
+
+// phoenix returns a PointFunc for the phoenix fractal. If julia is nil c is
+// the point being rendered and z starts at 0, like the mandelbrot set,
+// otherwise c is *julia and z starts at the point being rendered.
+func phoenix(color ColorFunc, bailout Bailout, p Complex, julia *Complex) PointFunc {
+	return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+		c, z := Complex{xCoord, yCoord}, Complex{0, 0}
+		if julia != nil {
+			c, z = *julia, Complex{xCoord, yCoord}
+		}
+		y := Complex{0, 0}
+		iterations := 0
+
+		iterate := func(z Complex) Complex {
+			next := z.Sq().Add(c).Add(p.Mul(y))
+			y = z
+			return next
+		}
+
+		for iterations = 0; !bailout.Escaped(z) && iterations < iterationCap; iterations++ {
+			z = iterate(z)
+		}
+
+		return color(
+			iterations,
+			iterationCap,
+			map[string]interface{}{
+				"z":        z,
+				"iterator": iterate,
+				"bailout":  bailout,
+			},
+		)
+	}
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"math"
+)
+
+// polynomial is a polynomial's coefficients, highest power first
+type polynomial []Complex
+
+// polynomialFromRoots returns the monic polynomial with the given roots
+func polynomialFromRoots(roots []Complex) polynomial {
+	p := polynomial{{1, 0}}
+	for _, root := range roots {
+		// Multiply by (z - root)
+		next := make(polynomial, len(p)+1)
+		for key, coeff := range p {
+			next[key] = next[key].Add(coeff)
+			next[key+1] = next[key+1].Sub(coeff.Mul(root))
+		}
+		p = next
+	}
+	return p
+}
+
+// degree returns the degree of the polynomial, ignoring any leading zero coefficients
+func (p polynomial) degree() int {
+	for key, coeff := range p {
+		if coeff.Real != 0 || coeff.Imag != 0 {
+			return len(p) - 1 - key
+		}
+	}
+	return 0
+}
+
+// eval returns the value and the derivative of the polynomial at z, by Horner's method
+func (p polynomial) eval(z Complex) (value, derivative Complex) {
+	for _, coeff := range p {
+		derivative = derivative.Mul(z).Add(value)
+		value = value.Mul(z).Add(coeff)
+	}
+	return value, derivative
+}
+
+// roots finds every root of the polynomial with the Durand-Kerner method
+func (p polynomial) roots() []Complex {
+	degree := p.degree()
+	if degree < 1 {
+		return nil
+	}
+
+	// Make the polynomial monic
+	lead := p[len(p)-1-degree]
+	monic := make(polynomial, degree+1)
+	for key := range monic {
+		monic[key] = p[len(p)-1-degree+key].Div(lead)
+	}
+
+	// Start from points spread around a circle that isn't symmetric with the real axis
+	roots := make([]Complex, degree)
+	start := Complex{0.4, 0.9}
+	roots[0] = Complex{1, 0}
+	for key := 1; key < degree; key++ {
+		roots[key] = roots[key-1].Mul(start)
+	}
+
+	for iteration := 0; iteration < 500; iteration++ {
+		change := 0.0
+		for key := range roots {
+			denominator := Complex{1, 0}
+			for other := range roots {
+				if other != key {
+					denominator = denominator.Mul(roots[key].Sub(roots[other]))
+				}
+			}
+			value, _ := monic.eval(roots[key])
+			delta := value.Div(denominator)
+			roots[key] = roots[key].Sub(delta)
+			change = math.Max(change, delta.Abs())
+		}
+		if change < 1e-14 {
+			break
+		}
+	}
+	return roots
+}
+
+// nearestRoot returns the index of the root closest to z, if it's within tolerance, or -1
+func nearestRoot(z Complex, roots []Complex, tolerance float64) int {
+	nearest, nearestDistance := -1, tolerance
+	for key, root := range roots {
+		if distance := z.Sub(root).Abs(); distance < nearestDistance {
+			nearest, nearestDistance = key, distance
+		}
+	}
+	return nearest
+}
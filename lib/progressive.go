@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// progressiveStep is the spacing of the pixels rendered by the first pass of a progressive render
+const progressiveStep = 16
+
+// GenerateProgressive renders the image in passes of increasing resolution,
+// so there's something to show straight away. The first pass renders every
+// 16th pixel of every 16th row, and each pass after it halves the spacing,
+// rendering just the pixels the passes before it haven't, until the last pass
+// renders the rest with a spacing of 1. Every pixel is rendered once, exactly
+// as Generate renders it, so a progressive render does no more work and ends
+// up with the same image.
+//
+// After each pass the pixels still to be rendered are filled in with the
+// rendered pixel above and to the left of them, and frame is called with the
+// pass's spacing. Img can be read until frame returns, but is written to again
+// after. If frame returns false, the render stops there, so a render can be
+// stopped once it's good enough. GenerateContext's cancellation applies too.
+func (f Generator) GenerateProgressive(ctx context.Context, frame func(step int) bool) error {
+	atomic.StoreInt64(f.done, 0)
+
+	for step := progressiveStep; step >= 1; step /= 2 {
+		var wg sync.WaitGroup
+		wg.Add(f.routines)
+		for routine := 0; routine < f.routines; routine++ {
+			go f.passRoutine(ctx.Done(), &wg, routine, step, step == progressiveStep)
+		}
+		wg.Wait()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		f.fillPass(step)
+		if !frame(step) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// passRoutine renders a routine's share of the rows of a progressive pass,
+// skipping the pixels rendered by the pass before, unless it's the first
+func (f Generator) passRoutine(stop <-chan struct{}, wg *sync.WaitGroup, rno, step int, first bool) {
+	defer wg.Done()
+
+	px := f.newPixelRenderer()
+	region := f.Img.Bounds()
+
+	// Cancellation is checked for a row at a time, as in genRoutine
+	for row := rno; row*step < region.Dy(); row += f.routines {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		yOffset := row * step
+		done := int64(0)
+		for xOffset := 0; xOffset < region.Dx(); xOffset += step {
+			if !first && xOffset%(2*step) == 0 && yOffset%(2*step) == 0 {
+				continue
+			}
+			if f.known == nil || !f.known[yOffset*region.Dx()+xOffset] {
+				px.render(region.Min.X+xOffset, region.Min.Y+yOffset)
+			}
+			done++
+		}
+		atomic.AddInt64(f.done, done)
+	}
+}
+
+// fillPass fills in each pixel not yet rendered after a pass with the
+// spacing, with the rendered pixel above and to the left of it. Pixels a
+// FrameCache has filled in are left be.
+func (f Generator) fillPass(step int) {
+	if step == 1 {
+		return
+	}
+	img := f.Img
+	region := img.Bounds()
+	rowLength := region.Dx() * 4
+
+	for yOffset := 0; yOffset < region.Dy(); yOffset++ {
+		row := img.Pix[img.PixOffset(region.Min.X, region.Min.Y+yOffset):][:rowLength]
+		from := img.Pix[img.PixOffset(region.Min.X, region.Min.Y+yOffset-yOffset%step):][:rowLength]
+		if yOffset%step != 0 && f.known == nil {
+			copy(row, from)
+			continue
+		}
+		for xOffset := 0; xOffset < region.Dx(); xOffset++ {
+			if (xOffset%step != 0 || yOffset%step != 0) && (f.known == nil || !f.known[yOffset*region.Dx()+xOffset]) {
+				copy(row[xOffset*4:][:4], from[(xOffset-xOffset%step)*4:])
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingPointFunc wraps a point func, counting how many times each point is rendered
+func countingPointFunc(fn PointFunc) (PointFunc, map[[2]float64]int, *int64) {
+	var mu sync.Mutex
+	counts := map[[2]float64]int{}
+	calls := new(int64)
+	return func(xCoord, yCoord float64, iterationCap int) (R, G, B, A float64) {
+		mu.Lock()
+		counts[[2]float64{xCoord, yCoord}]++
+		mu.Unlock()
+		atomic.AddInt64(calls, 1)
+		return fn(xCoord, yCoord, iterationCap)
+	}, counts, calls
+}
+
+func TestGenerateProgressive(t *testing.T) {
+	pointFunc, err := GetPointFunc("mandelbrot", "smoothcolor", nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range [][2]int{{37, 23}, {64, 64}, {5, 3}, {100, 41}} {
+		width, height := size[0], size[1]
+		want := NewGenerator(width, height, 3, 64, 1, -0.5, 0, 0.8, pointFunc)
+		want.Generate()
+
+		counting, counts, calls := countingPointFunc(pointFunc)
+		gen := NewGenerator(width, height, 3, 64, 1, -0.5, 0, 0.8, counting)
+		steps := []int{}
+		err := gen.GenerateProgressive(context.Background(), func(step int) bool {
+			// By the end of a pass every pixel on its grid has been rendered
+			rendered := ((width + step - 1) / step) * ((height + step - 1) / step)
+			if done := atomic.LoadInt64(gen.done); done != int64(rendered) {
+				t.Errorf("%dx%d: after the pass with a spacing of %d, done is %d, want %d", width, height, step, done, rendered)
+			}
+			if n := atomic.LoadInt64(calls); n != int64(rendered) {
+				t.Errorf("%dx%d: after the pass with a spacing of %d, %d pixels were rendered, want %d", width, height, step, n, rendered)
+			}
+			steps = append(steps, step)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(steps) != 5 || steps[0] != progressiveStep || steps[4] != 1 {
+			t.Errorf("%dx%d: the passes had spacings of %v", width, height, steps)
+		}
+		if len(counts) != width*height {
+			t.Errorf("%dx%d: %d points were rendered, want %d", width, height, len(counts), width*height)
+		}
+		for point, count := range counts {
+			if count != 1 {
+				t.Errorf("%dx%d: %v was rendered %d times", width, height, point, count)
+				break
+			}
+		}
+		if gen.Progress() != 1 {
+			t.Errorf("%dx%d: the progress is %v, want 1", width, height, gen.Progress())
+		}
+		if !bytes.Equal(gen.Img.Pix, want.Img.Pix) {
+			t.Errorf("%dx%d: the image isn't the same as Generate's", width, height)
+		}
+	}
+}
+
+func TestGenerateProgressiveStopped(t *testing.T) {
+	pointFunc, err := GetPointFunc("mandelbrot", "smoothcolor", nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting, _, calls := countingPointFunc(pointFunc)
+	gen := NewGenerator(64, 64, 2, 64, 1, -0.5, 0, 0.8, counting)
+
+	passes := 0
+	if err := gen.GenerateProgressive(context.Background(), func(step int) bool {
+		passes++
+		return false
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if passes != 1 || *calls != 16 {
+		t.Errorf("stopping after the first pass took %d passes and rendered %d pixels, want 1 and 16", passes, *calls)
+	}
+}
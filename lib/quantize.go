@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// maxQuantizeSamples caps how many pixels MedianCut looks at, spread evenly
+// across all of the images, so building a palette for a long animation is cheap.
+const maxQuantizeSamples = 1 << 18
+
+// MedianCut builds a palette of up to n colors that suits the given images,
+// by repeatedly splitting the most spread out box of colors at its median.
+func MedianCut(imgs []*image.NRGBA, n int) color.Palette {
+	total := 0
+	for _, img := range imgs {
+		total += len(img.Pix) / 4
+	}
+	if total == 0 || n < 1 {
+		return color.Palette{}
+	}
+	stride := total/maxQuantizeSamples + 1
+
+	pixels := make([][4]uint8, 0, total/stride+1)
+	for _, img := range imgs {
+		for i := 0; i < len(img.Pix); i += 4 * stride {
+			pixels = append(pixels, [4]uint8{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]})
+		}
+	}
+
+	boxes := [][][4]uint8{pixels}
+	for len(boxes) < n {
+		// Split the box with the widest range in any channel
+		widest, widestChannel, widestRange := -1, 0, 0
+		for key, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, spread := boxRange(box)
+			if spread > widestRange {
+				widest, widestChannel, widestRange = key, channel, spread
+			}
+		}
+		if widest < 0 {
+			break
+		}
+
+		box := boxes[widest]
+		sort.Slice(box, func(i, j int) bool {
+			return box[i][widestChannel] < box[j][widestChannel]
+		})
+		split := medianSplit(box, widestChannel)
+		boxes[widest] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make(color.Palette, len(boxes))
+	for key, box := range boxes {
+		var sum [4]int
+		for _, pixel := range box {
+			for channel := range sum {
+				sum[channel] += int(pixel[channel])
+			}
+		}
+		palette[key] = color.NRGBA{
+			uint8(sum[0] / len(box)),
+			uint8(sum[1] / len(box)),
+			uint8(sum[2] / len(box)),
+			uint8(sum[3] / len(box)),
+		}
+	}
+	return palette
+}
+
+// medianSplit returns where to split a box sorted by the channel: the
+// boundary between two values of the channel nearest the median, so no value
+// ends up in both halves, wasting a color of the palette on a duplicate
+func medianSplit(box [][4]uint8, channel int) int {
+	mid := len(box) / 2
+	for offset := 0; offset < len(box); offset++ {
+		for _, split := range []int{mid - offset, mid + offset} {
+			if split > 0 && split < len(box) && box[split-1][channel] != box[split][channel] {
+				return split
+			}
+		}
+	}
+	return mid
+}
+
+// boxRange returns the channel with the largest range in a box of colors, and that range
+func boxRange(box [][4]uint8) (channel, spread int) {
+	min := box[0]
+	max := box[0]
+	for _, pixel := range box {
+		for c := range pixel {
+			if pixel[c] < min[c] {
+				min[c] = pixel[c]
+			}
+			if pixel[c] > max[c] {
+				max[c] = pixel[c]
+			}
+		}
+	}
+	for c := range min {
+		if int(max[c])-int(min[c]) > spread {
+			channel, spread = c, int(max[c])-int(min[c])
+		}
+	}
+	return channel, spread
+}
@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// colorfulImage returns an image with a different color for nearly every pixel
+func colorfulImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+	return img
+}
+
+func TestMedianCutSize(t *testing.T) {
+	// The second image has more pixels than are sampled
+	imgs := []*image.NRGBA{colorfulImage(256, 256), colorfulImage(600, 500)}
+	for _, n := range []int{256, 16, 1} {
+		if palette := MedianCut(imgs, n); len(palette) == 0 || len(palette) > n {
+			t.Errorf("a palette of up to %d colours has %d", n, len(palette))
+		}
+	}
+	if palette := MedianCut(imgs, 0); len(palette) != 0 {
+		t.Errorf("a palette of no colours has %d", len(palette))
+	}
+	if palette := MedianCut(nil, 256); len(palette) != 0 {
+		t.Errorf("the palette of no images has %d colours", len(palette))
+	}
+}
+
+func TestMedianCutFewColors(t *testing.T) {
+	colors := []color.NRGBA{{255, 0, 0, 255}, {0, 128, 255, 255}, {10, 10, 10, 0}}
+	img := image.NewNRGBA(image.Rect(0, 0, 30, 30))
+	for key := 0; key < len(img.Pix)/4; key++ {
+		img.SetNRGBA(key%30, key/30, colors[key%len(colors)])
+	}
+
+	// An image with fewer colours than the palette gets exactly its colours
+	palette := MedianCut([]*image.NRGBA{img}, 256)
+	if len(palette) != len(colors) {
+		t.Fatalf("the palette has %d colours, want %d", len(palette), len(colors))
+	}
+	for _, c := range colors {
+		if palette[palette.Index(c)] != c {
+			t.Errorf("%v isn't in the palette %v", c, palette)
+		}
+	}
+}
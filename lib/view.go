@@ -0,0 +1,163 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Errors for views
+var (
+	ErrInvalidBounds = errors.New("bounds must have xmin < xmax and ymin < ymax")
+	ErrInvalidView   = errors.New("invalid view")
+)
+
+// Bounds is a rectangular region of the complex plane
+type Bounds struct {
+	XMin, XMax, YMin, YMax float64
+}
+
+// String formats bounds the same way they are given to the -bounds flag
+func (b Bounds) String() string {
+	return fmt.Sprintf("%g,%g,%g,%g", b.XMin, b.XMax, b.YMin, b.YMax)
+}
+
+// Valid checks the bounds have some area to them
+func (b Bounds) Valid() error {
+	if !(b.XMin < b.XMax && b.YMin < b.YMax) {
+		return ErrInvalidBounds
+	}
+	return nil
+}
+
+// Contains checks if a point lies within the bounds
+func (b Bounds) Contains(xCoord, yCoord float64) bool {
+	return b.XMin <= xCoord && xCoord <= b.XMax && b.YMin <= yCoord && yCoord <= b.YMax
+}
+
+// FitBounds returns the centre and zoom factor for NewGenerator that fit the
+// bounds into an image of the given size as tightly as possible. Along the
+// image's longer side (relative to the bounds) the view extends past the bounds.
+func FitBounds(width, height int, b Bounds) (xPos, yPos, zoom float64) {
+	scale := math.Max((b.XMax-b.XMin)/float64(width), (b.YMax-b.YMin)/float64(height))
+	zoom = 2 / (scale * scaler(width, height))
+
+	/* The generator's centre is at pixel (width/2, height/2), which is half
+	   a pixel right of and below the middle of the image.
+	*/
+	xPos = (b.XMin+b.XMax)/2 + scale/2
+	yPos = (b.YMin+b.YMax)/2 + scale/2
+	return xPos, yPos, zoom
+}
+
+// Framing is the region of the complex plane an image shows, given in one of
+// three ways, in the generator's orientation. The first of them given is used.
+type Framing struct {
+	Bounds     *Bounds // the region itself
+	Radius     float64 // or the radius of a square around the centre
+	PlaneWidth float64 // or the width of a region around the centre, with the image's aspect ratio
+
+	// Fit is how the region fits an image with a different aspect ratio:
+	// expand (the default) shows more of the plane along the longer side,
+	// letterbox leaves the rest of the image transparent, and height works
+	// out the image's height from its width.
+	Fit string
+}
+
+// View is where a generator looks, in its orientation
+type View struct {
+	XPos, YPos, Zoom float64
+	Height           int
+	Clip             *Bounds // the region, if it's letterboxed
+}
+
+// Frame returns the view for an image centred on (xPos, yPos) at the zoom
+// factor, or fitted to the framing's region if it has one. The region is
+// fitted before any rotation or transform.
+func (fr Framing) Frame(width, height int, xPos, yPos, zoom float64) (View, error) {
+	view := View{xPos, yPos, zoom, height, nil}
+	if width < 1 {
+		return view, fmt.Errorf("%w: the width must be at least 1", ErrInvalidView)
+	}
+
+	var region Bounds
+	switch {
+	case fr.Bounds != nil:
+		region = *fr.Bounds
+	case fr.Radius > 0:
+		region = Bounds{
+			XMin: xPos - fr.Radius, XMax: xPos + fr.Radius,
+			YMin: yPos - fr.Radius, YMax: yPos + fr.Radius,
+		}
+	case fr.PlaneWidth > 0:
+		halfHeight := fr.PlaneWidth * float64(height) / float64(2*width)
+		region = Bounds{
+			XMin: xPos - fr.PlaneWidth/2, XMax: xPos + fr.PlaneWidth/2,
+			YMin: yPos - halfHeight, YMax: yPos + halfHeight,
+		}
+	default:
+		if !(zoom > 0) {
+			return view, fmt.Errorf("%w: the zoom factor must be more than 0", ErrInvalidView)
+		}
+		if height < 1 {
+			return view, fmt.Errorf("%w: the height must be at least 1", ErrInvalidView)
+		}
+		return view, nil
+	}
+	if err := region.Valid(); err != nil {
+		return view, err
+	}
+
+	switch strings.ToLower(fr.Fit) {
+	case "", "expand":
+	case "letterbox":
+		view.Clip = &region
+	case "height":
+		view.Height = HeightForBounds(width, region)
+	default:
+		return view, fmt.Errorf("%w: invalid fit %q", ErrInvalidView, fr.Fit)
+	}
+	if view.Height < 1 {
+		return view, fmt.Errorf("%w: the height must be at least 1", ErrInvalidView)
+	}
+
+	view.XPos, view.YPos, view.Zoom = FitBounds(width, view.Height, region)
+	return view, nil
+}
+
+// HeightForBounds returns the image height that gives the bounds the same aspect ratio as the image
+func HeightForBounds(width int, b Bounds) int {
+	height := int(math.Round(float64(width) * (b.YMax - b.YMin) / (b.XMax - b.XMin)))
+	if height < 1 {
+		height = 1
+	}
+	return height
+}
+
+// Bounds returns the region of the complex plane covered by the generator's
+// image. If the view is rotated or skewed it is the smallest region
+// containing all of the image.
+func (f Generator) Bounds() Bounds {
+	if f.expMap {
+		radius := expMapOuterRadius / f.zoom
+		return Bounds{f.xPos - radius, f.xPos + radius, f.yPos - radius, f.yPos + radius}
+	}
+
+	b := Bounds{math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)}
+	for _, xPix := range []float64{-0.5, float64(f.width) - 0.5} {
+		for _, yPix := range []float64{-0.5, float64(f.height) - 0.5} {
+			xCoord, yCoord := f.pixToCoord(xPix, yPix)
+			b.XMin, b.XMax = math.Min(b.XMin, xCoord), math.Max(b.XMax, xCoord)
+			b.YMin, b.YMax = math.Min(b.YMin, yCoord), math.Max(b.YMax, yCoord)
+		}
+	}
+	return b
+}
+
+// SetClip leaves any pixel whose centre lies outside the bounds transparent,
+// e.g. to letterbox a region that does not fill the image.
+func (f *Generator) SetClip(b Bounds) {
+	f.clip = &b
+	f.known = nil
+}
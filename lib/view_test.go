@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFrameInvalid(t *testing.T) {
+	region := Bounds{-2, 1, -1, 1}
+	tests := []struct {
+		name          string
+		framing       Framing
+		width, height int
+		zoom          float64
+	}{
+		{"zero zoom", Framing{}, 100, 100, 0},
+		{"negative zoom", Framing{}, 100, 100, -1},
+		{"zero width", Framing{}, 0, 100, 1},
+		{"negative height", Framing{}, 100, -5, 1},
+		{"zero width with bounds", Framing{Bounds: &region}, 0, 100, 1},
+		{"zero height with bounds", Framing{Bounds: &region}, 100, 0, 1},
+		{"zero width with a radius", Framing{Radius: 1}, 0, 100, 1},
+		{"unknown fit", Framing{Radius: 1, Fit: "stretch"}, 100, 100, 1},
+	}
+	for _, test := range tests {
+		if _, err := test.framing.Frame(test.width, test.height, 0, 0, test.zoom); !errors.Is(err, ErrInvalidView) {
+			t.Errorf("%s: got %v, want %v", test.name, err, ErrInvalidView)
+		}
+	}
+}
+
+func TestFrameFit(t *testing.T) {
+	region := Bounds{-2, 1, -1, 1}
+	view, err := Framing{Bounds: &region, Fit: "Letterbox"}.Frame(300, 300, 0, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Clip == nil || *view.Clip != region {
+		t.Errorf("a letterboxed view clips to %v, want %v", view.Clip, region)
+	}
+
+	view, err = Framing{Bounds: &region, Fit: "height"}.Frame(300, 300, 0, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Height != 200 || view.Clip != nil {
+		t.Errorf("fitting the height gives %d, want 200", view.Height)
+	}
+}
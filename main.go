@@ -1,61 +1,98 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"image/png"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/theteacat/romanesgo/lib"
+	"github.com/theteacat/romanesgo/server"
 )
 
+// commands are the subcommands available in lieu of a plain render,
+// e.g. "romanesgo animate -kf=keyframes.json"
+var commands = map[string]func(args []string){
+	"animate":    animate,
+	"buddhabrot": buddhabrot,
+	"explore":    explore,
+	"ifs":        ifs,
+	"preview":    preview,
+	"serve":      serve,
+	"unroll":     unroll,
+	"worker":     worker,
+}
+
 func main() {
-	fractalName := flag.String("ff", "none", "fractal")
-	var constants flagConstants
-	flag.Var(&constants, "c", "constants")
-	iterations := flag.Int("i", 128, "maximum iterations")
-	colorName := flag.String("cf", "default", "coloring function")
-	xCentre := flag.Float64("x", 0, "central x coord")
-	yCentre := flag.Float64("y", 0, "central y coord")
-	zoom := flag.Float64("z", 1, "zoom factor")
-	width := flag.Int("w", 1000, "image width")
-	height := flag.Int("h", 1000, "image height")
-	samples := flag.Int("ss", 1, "supersampling factor")
-	routines := flag.Int("r", runtime.NumCPU(), "goroutines used")
+	if len(os.Args) > 1 {
+		if command, exists := commands[os.Args[1]]; exists {
+			command(os.Args[2:])
+			return
+		}
+	}
+
+	rf := addRenderFlags(flag.CommandLine)
 	fn := flag.String("fn", "temp.png", "filename")
+	expMapDepth := flag.Float64("em", 0, "render an exponential map strip from the zoom factor down to this zoom factor, for romanesgo unroll (0 is off)")
+	atlas := flag.Int("atlas", 0, "render a grid of this many by this many julia sets, for points spread across the view (0 is off)")
+	atlasZoom := flag.Float64("az", 1, "zoom factor of each julia set in -atlas")
+	workers := flag.String("workers", "", `render across romanesgo worker processes at these comma separated addresses, e.g. "localhost:9001,localhost:9002"`)
 	flag.Parse()
 
 	args := flag.Args()
 
-	if *fractalName == "none" || len(args) > 0 && args[0] == "help" {
+	if *rf.fractalName == "none" && *rf.formula == "" || len(args) > 0 && args[0] == "help" {
 		handleHelp(args)
 	} else {
-		pointFunc, err := lib.GetPointFunc(*fractalName, *colorName, constants)
+		spec := rf.spec()
+		fatal(rf.view())
+
+		pointFunc, err := rf.pointFunc(rf.constants, rf.options())
 		fatal(err)
 
-		fmt.Print("\n\tFractal (ff):\t\t", *fractalName,
-			"\n\tConstants (c):\t\t", constants.String(),
-			"\n\tMax Iterations (i):\t", *iterations,
-			"\n\tColoring function (cf):\t", *colorName,
-			"\n\tCentre x Coord (x):\t", *xCentre,
-			"\n\tCentre y Coord (y):\t", *yCentre,
-			"\n\tZoom factor (z):\t", *zoom,
-			"\n\tImage Width (w):\t", *width,
-			"\n\tImage Height (h):\t", *height,
-			"\n\tSupersampling (ss):\t", *samples,
-			"\n\tRoutines (r):\t\t", *routines,
-			"\n\tFilename (png) (fn):\t", *fn, "\n\n")
+		rf.print()
+
+		if *atlas > 0 && (*expMapDepth != 0 || rf.juliaPoint.set || *rf.formula != "") {
+			fatal(errors.New("-atlas can't be used with -em, -jc or -fx"))
+		}
+		if *workers != "" {
+			if *atlas > 0 || *expMapDepth != 0 {
+				fatal(errors.New("-workers can't be used with -atlas or -em"))
+			}
+			distribute(spec, strings.Split(*workers, ","), *fn)
+			return
+		}
 
-		gen := lib.NewGenerator(*width, *height, *routines, *iterations, *samples, *xCentre, -*yCentre, *zoom, pointFunc)
+		gen := rf.generator(pointFunc)
+		if *expMapDepth != 0 {
+			gen, err = lib.NewExpMapGenerator(*rf.width, *rf.routines, *rf.iterations, *rf.samples, *rf.xCentre, -*rf.yCentre, *rf.zoom, *expMapDepth, pointFunc)
+			fatal(err)
+			rf.transform(&gen)
+			fmt.Print("\tExp map depth (em):\t", *expMapDepth,
+				"\n\tExp map height:\t\t", gen.Img.Bounds().Dy(), "\n")
+		}
+		if *atlas > 0 {
+			fmt.Print("\tJulia atlas (atlas):\t", *atlas,
+				"\n\tJulia zoom (az):\t", *atlasZoom, "\n")
+		}
+		fmt.Print("\tBounds (bounds):\t", flipBounds(gen.Bounds()),
+			"\n\tFilename (png) (fn):\t", *fn, "\n\n")
 
 		newFile, err := os.Create(*fn)
 		fatal(err)
 
 		timeIt(func() {
-			gen.Generate()
+			if *atlas > 0 {
+				fatal(lib.JuliaAtlas(gen, *atlas, *atlasZoom, *rf.fractalName, *rf.colorName, rf.constants, rf.options()))
+			} else {
+				gen.Generate()
+			}
 
 			err = png.Encode(newFile, gen.Img)
 			fatal(err)
@@ -63,6 +100,234 @@ func main() {
 	}
 }
 
+// renderFlags are the flags shared by every command that renders a fractal
+type renderFlags struct {
+	fractalName   *string
+	formula       *string
+	bailout       *string
+	bailoutRadius *float64
+	norm          flagNorm
+	constants     flagConstants
+	strs          flagStrings
+	params        flagParams
+	juliaPoint    flagPoint
+	iterations    *int
+	colorName     *string
+	paletteOffset *float64
+	rotation      *float64
+	matrix        flagMatrix
+	*viewFlags
+}
+
+// viewFlags are the flags for the region of the plane to render and the image
+// it's rendered into, which every renderer of the plane shares
+type viewFlags struct {
+	xCentre    *float64
+	yCentre    *float64
+	zoom       *float64
+	bounds     flagBounds
+	radius     *float64
+	planeWidth *float64
+	fit        *string
+	clip       *lib.Bounds
+	width      *int
+	height     *int
+	samples    *int
+	routines   *int
+}
+
+func addRenderFlags(fs *flag.FlagSet) *renderFlags {
+	rf := &renderFlags{}
+	rf.fractalName = fs.String("ff", "none", "fractal")
+	rf.formula = fs.String("fx", "", `formula to use in lieu of -ff, e.g. "z^3 + c*sin(z)"`)
+	rf.bailout = fs.String("fxb", "", `bailout condition for -fx in lieu of -br and -bn, e.g. "re(z) > 4"`)
+	rf.bailoutRadius = fs.Float64("br", 0, "bailout radius, or 0 for the fractal's own")
+	rf.norm = flagNorm{name: "euclidean"}
+	fs.Var(&rf.norm, "bn", "bailout norm: euclidean, manhattan, max, real or imag")
+	fs.Var(&rf.constants, "c", "constants")
+	fs.Var(&rf.strs, "s", "string constants, for fractals that take them such as lyapunov")
+	rf.params = flagParams{}
+	fs.Var(&rf.params, "p", `named parameter, as "name=value" e.g. "power=3" or "c=-0.2+0.65i", which can be repeated and takes precedence over -c and -s`)
+	fs.Var(&rf.juliaPoint, "jc", `render the julia set for this point of the fractal's parameter plane, given as "x,y" like -x and -y`)
+	rf.iterations = fs.Int("i", 128, "maximum iterations")
+	rf.colorName = fs.String("cf", "default", "coloring function")
+	rf.paletteOffset = fs.Float64("po", 0, "palette offset")
+	rf.rotation = fs.Float64("rot", 0, "rotation in degrees")
+	rf.matrix = flagMatrix{1, 0, 0, 1}
+	fs.Var(&rf.matrix, "m", `2x2 matrix applied to the view before rotating it, for skewing and stretching (e.g. "1,0.5,0,1")`)
+	rf.viewFlags = addViewFlags(fs)
+	return rf
+}
+
+func addViewFlags(fs *flag.FlagSet) *viewFlags {
+	vf := &viewFlags{}
+	vf.xCentre = fs.Float64("x", 0, "central x coord")
+	vf.yCentre = fs.Float64("y", 0, "central y coord")
+	vf.zoom = fs.Float64("z", 1, "zoom factor")
+	fs.Var(&vf.bounds, "bounds", `region to view in lieu of -x, -y and -z, as "xmin,xmax,ymin,ymax"`)
+	vf.radius = fs.Float64("rad", 0, "radius of the region to view around -x and -y, in lieu of -z")
+	vf.planeWidth = fs.Float64("pw", 0, "width of the region to view around -x and -y, in lieu of -z")
+	vf.fit = fs.String("fit", "expand", "how -bounds and -rad fit the image: expand (view more of the plane along the longer side), letterbox (leave the rest of the image transparent) or height (work out -h from -w)")
+	vf.width = fs.Int("w", 1000, "image width")
+	vf.height = fs.Int("h", 1000, "image height")
+	vf.samples = fs.Int("ss", 1, "supersampling factor")
+	vf.routines = fs.Int("r", runtime.NumCPU(), "goroutines used")
+	return vf
+}
+
+// pointFunc gets a PointFunc for either the formula or the fractal
+func (rf *renderFlags) pointFunc(constants []float64, opts lib.Options) (lib.PointFunc, error) {
+	if *rf.formula != "" {
+		if rf.juliaPoint.set {
+			return nil, errors.New("-jc needs a fractal from -ff")
+		}
+		return lib.GetFormulaPointFunc(*rf.formula, *rf.bailout, *rf.colorName, constants, opts)
+	}
+	fractalName := *rf.fractalName
+	if rf.juliaPoint.set {
+		if _, named := rf.params["c"]; named {
+			return nil, errors.New("-jc sets c, so it can't be given with -p too")
+		}
+		var err error
+		fractalName, constants, err = lib.JuliaConstants(fractalName, constants, rf.juliaPoint.x, -rf.juliaPoint.y)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lib.GetPointFunc(fractalName, *rf.colorName, constants, opts)
+}
+
+func (rf *renderFlags) options() lib.Options {
+	return lib.Options{
+		PaletteOffset: *rf.paletteOffset,
+		Strings:       rf.strs,
+		Params:        rf.params,
+		BailoutRadius: *rf.bailoutRadius,
+		BailoutNorm:   rf.norm.Norm,
+	}
+}
+
+// spec returns the render the flags describe, as given, for workers to render
+func (rf *renderFlags) spec() server.RenderSpec {
+	spec := server.RenderSpec{
+		Fractal:        *rf.fractalName,
+		Formula:        *rf.formula,
+		FormulaBailout: *rf.bailout,
+		BailoutRadius:  *rf.bailoutRadius,
+		BailoutNorm:    rf.norm.name,
+		Constants:      rf.constants,
+		Strings:        rf.strs,
+		Params:         rf.params,
+		Iterations:     *rf.iterations,
+		Color:          *rf.colorName,
+		PaletteOffset:  *rf.paletteOffset,
+		X:              *rf.xCentre,
+		Y:              *rf.yCentre,
+		Zoom:           *rf.zoom,
+		Rotation:       *rf.rotation,
+		Matrix:         rf.matrix,
+		Radius:         *rf.radius,
+		PlaneWidth:     *rf.planeWidth,
+		Fit:            *rf.fit,
+		Width:          *rf.width,
+		Height:         *rf.height,
+		Samples:        *rf.samples,
+	}
+	if rf.juliaPoint.set {
+		spec.JuliaPoint = &[2]float64{rf.juliaPoint.x, rf.juliaPoint.y}
+	}
+	if rf.bounds.set {
+		spec.Bounds = &[4]float64{rf.bounds.XMin, rf.bounds.XMax, rf.bounds.YMin, rf.bounds.YMax}
+	}
+	return spec
+}
+
+func (rf *renderFlags) generator(pointFunc lib.PointFunc) lib.Generator {
+	gen := lib.NewGenerator(*rf.width, *rf.height, *rf.routines, *rf.iterations, *rf.samples, *rf.xCentre, -*rf.yCentre, *rf.zoom, pointFunc)
+	rf.transform(&gen)
+	return gen
+}
+
+// transform applies the rotation, matrix and letterboxing flags to a generator
+func (rf *renderFlags) transform(gen *lib.Generator) {
+	gen.SetTransform(rf.matrix[0], rf.matrix[1], rf.matrix[2], rf.matrix[3])
+	gen.SetView(*rf.xCentre, -*rf.yCentre, *rf.zoom, *rf.rotation)
+	if rf.clip != nil {
+		gen.SetClip(*rf.clip)
+	}
+}
+
+// setDefault changes the default of a flag that hasn't been parsed yet
+func setDefault(fs *flag.FlagSet, name, value string) {
+	f := fs.Lookup(name)
+	f.Value.Set(value)
+	f.DefValue = value
+}
+
+// view works out the centre, zoom factor and image height from -bounds, -rad
+// or -pw, if any of them were given, and checks the view and image are valid.
+// The region is fitted to the image before -rot and -m are applied.
+func (vf *viewFlags) view() error {
+	if *vf.samples < 1 || *vf.routines < 1 {
+		return errors.New("-ss and -r must be at least 1")
+	}
+	framing := lib.Framing{Radius: *vf.radius, PlaneWidth: *vf.planeWidth, Fit: *vf.fit}
+	if vf.bounds.set {
+		// The generator's y axis is flipped relative to -y, so the region is flipped to match it
+		region := flipBounds(vf.bounds.Bounds)
+		framing.Bounds = &region
+	}
+	view, err := framing.Frame(*vf.width, *vf.height, *vf.xCentre, -*vf.yCentre, *vf.zoom)
+	if err != nil {
+		return err
+	}
+
+	*vf.xCentre, *vf.yCentre, *vf.zoom, *vf.height = view.XPos, -view.YPos, view.Zoom, view.Height
+	vf.clip = view.Clip
+	return nil
+}
+
+// flipBounds flips bounds between the orientation of -y and the orientation of the generator
+func flipBounds(b lib.Bounds) lib.Bounds {
+	return lib.Bounds{XMin: b.XMin, XMax: b.XMax, YMin: -b.YMax, YMax: -b.YMin}
+}
+
+func (rf *renderFlags) print() {
+	if *rf.formula != "" {
+		fmt.Print("\n\tFormula (fx):\t\t", *rf.formula)
+	} else {
+		fmt.Print("\n\tFractal (ff):\t\t", *rf.fractalName)
+	}
+	if rf.juliaPoint.set {
+		fmt.Print("\n\tJulia set for (jc):\t", rf.juliaPoint.String())
+	}
+	if *rf.formula != "" && *rf.bailout != "" {
+		fmt.Print("\n\tBailout (fxb):\t\t", *rf.bailout)
+	} else {
+		fmt.Print("\n\tBailout radius (br):\t", *rf.bailoutRadius,
+			"\n\tBailout norm (bn):\t", rf.norm.String())
+	}
+	fmt.Print("\n\tConstants (c):\t\t", rf.constants.String())
+	if len(rf.strs) > 0 {
+		fmt.Print("\n\tString constants (s):\t", rf.strs.String())
+	}
+	if len(rf.params) > 0 {
+		fmt.Print("\n\tParameters (p):\t\t", rf.params.String())
+	}
+	fmt.Print("\n\tMax Iterations (i):\t", *rf.iterations,
+		"\n\tColoring function (cf):\t", *rf.colorName,
+		"\n\tPalette offset (po):\t", *rf.paletteOffset,
+		"\n\tCentre x Coord (x):\t", *rf.xCentre,
+		"\n\tCentre y Coord (y):\t", *rf.yCentre,
+		"\n\tZoom factor (z):\t", *rf.zoom,
+		"\n\tRotation (rot):\t\t", *rf.rotation,
+		"\n\tMatrix (m):\t\t", rf.matrix.String(),
+		"\n\tImage Width (w):\t", *rf.width,
+		"\n\tImage Height (h):\t", *rf.height,
+		"\n\tSupersampling (ss):\t", *rf.samples,
+		"\n\tRoutines (r):\t\t", *rf.routines, "\n")
+}
+
 func handleHelp(args []string) {
 	if len(args) < 2 {
 		fmt.Println(`Do "romanesgo help {Fractal Name}" for further info on a particular fractal function.`)
@@ -71,6 +336,16 @@ func handleHelp(args []string) {
 			fmt.Println("\t", fname)
 		}
 
+		fmt.Println("\nCommands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("\t romanesgo %s -h\n", name)
+		}
+
 		fmt.Println("\nFlags:")
 		flag.PrintDefaults()
 	} else if len(args) == 2 {
@@ -114,3 +389,138 @@ func (f *flagConstants) Set(value string) error {
 	*f = append(*f, val)
 	return nil
 }
+
+// flagStrings is a list of strings, given by repeating the flag
+type flagStrings []string
+
+func (f *flagStrings) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *flagStrings) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// flagParams are named parameter values, given by repeating the flag as name=value
+type flagParams map[string]string
+
+func (f *flagParams) String() string {
+	names := make([]string, 0, len(*f))
+	for name := range *f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for key, name := range names {
+		names[key] = name + "=" + (*f)[name]
+	}
+	return strings.Join(names, ", ")
+}
+
+func (f *flagParams) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return errors.New(`parameters are given as "name=value"`)
+	}
+	(*f)[strings.TrimSpace(parts[0])] = parts[1]
+	return nil
+}
+
+// flagMatrix is a 2x2 matrix in row-major order, given as four comma separated numbers
+type flagMatrix [4]float64
+
+func (f *flagMatrix) String() string {
+	strs := make([]string, len(f))
+	for key, val := range f {
+		strs[key] = strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return strings.Join(strs, ",")
+}
+
+func (f *flagMatrix) Set(value string) error {
+	strs := strings.Split(value, ",")
+	if len(strs) != len(f) {
+		return errors.New("a matrix needs 4 comma separated values")
+	}
+	for key, str := range strs {
+		val, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil {
+			return err
+		}
+		f[key] = val
+	}
+	return nil
+}
+
+// flagBounds is a region of the complex plane, given as "xmin,xmax,ymin,ymax"
+type flagBounds struct {
+	lib.Bounds
+	set bool
+}
+
+func (f *flagBounds) Set(value string) error {
+	strs := strings.Split(value, ",")
+	if len(strs) != 4 {
+		return errors.New(`bounds must be given as "xmin,xmax,ymin,ymax"`)
+	}
+	vals := make([]float64, len(strs))
+	for key, str := range strs {
+		val, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil {
+			return err
+		}
+		vals[key] = val
+	}
+	f.Bounds = lib.Bounds{XMin: vals[0], XMax: vals[1], YMin: vals[2], YMax: vals[3]}
+	f.set = true
+	return nil
+}
+
+// flagNorm is the name of a bailout norm
+type flagNorm struct {
+	name string
+	lib.Norm
+}
+
+func (f *flagNorm) String() string {
+	return f.name
+}
+
+func (f *flagNorm) Set(value string) error {
+	norm, err := lib.GetNorm(value)
+	if err != nil {
+		return err
+	}
+	f.name, f.Norm = strings.ToLower(value), norm
+	return nil
+}
+
+// flagPoint is a point given as "x,y"
+type flagPoint struct {
+	x, y float64
+	set  bool
+}
+
+func (f *flagPoint) String() string {
+	if !f.set {
+		return ""
+	}
+	return strconv.FormatFloat(f.x, 'f', -1, 64) + "," + strconv.FormatFloat(f.y, 'f', -1, 64)
+}
+
+func (f *flagPoint) Set(value string) error {
+	strs := strings.Split(value, ",")
+	if len(strs) != 2 {
+		return errors.New(`a point must be given as "x,y"`)
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(strs[0]), 64)
+	if err != nil {
+		return err
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(strs[1]), 64)
+	if err != nil {
+		return err
+	}
+	f.x, f.y, f.set = x, y, true
+	return nil
+}
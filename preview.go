@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/theteacat/romanesgo/lib"
+)
+
+// The size assumed for a terminal that doesn't say, in characters, and for a
+// character when the terminal doesn't say how many pixels it has
+const (
+	defaultCols       = 80
+	defaultRows       = 24
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+)
+
+// previewReservedRows are the rows of the terminal left for the command line printed under a preview
+const previewReservedRows = 3
+
+// preview renders a fractal at the terminal's resolution and prints it, with
+// the command line that renders it in full, e.g.
+//
+//	romanesgo preview -ff=julia -c=-0.8 -c=0.156 -cf=smoothcolor
+//
+// The preview shows the same region as the full render, so it has the aspect
+// ratio of -w and -h.
+func preview(args []string) {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	rf := addRenderFlags(fs)
+	sixel := fs.Bool("sixel", false, "print a sixel image, for terminals that support them, in lieu of coloured half blocks")
+	cols := fs.Int("cols", 0, "width of the terminal in characters, or 0 to ask the terminal")
+	rows := fs.Int("rows", 0, "height of the terminal in characters, or 0 to ask the terminal")
+	fs.Parse(args)
+
+	if *rf.fractalName == "none" && *rf.formula == "" {
+		fatal(errors.New("preview needs -ff or -fx"))
+	}
+	fatal(rf.view())
+	pointFunc, err := rf.pointFunc(rf.constants, rf.options())
+	fatal(err)
+
+	termCols, termRows, termWidth, termHeight, err := terminalSize()
+	if err != nil || termCols == 0 || termRows == 0 {
+		termCols, termRows, termWidth, termHeight = defaultCols, defaultRows, 0, 0
+	}
+	if *cols > 0 {
+		termCols, termWidth = *cols, 0
+	}
+	if *rows > 0 {
+		termRows, termHeight = *rows, 0
+	}
+	termRows = int(math.Max(float64(termRows-previewReservedRows), 1))
+
+	// Half blocks are two pixels to a character, and sixels as many as the terminal has
+	maxWidth, maxHeight := termCols, 2*termRows
+	if *sixel {
+		cellWidth, cellHeight := defaultCellWidth, defaultCellHeight
+		if termWidth > 0 && termHeight > 0 {
+			cellWidth, cellHeight = termWidth/termCols, termHeight/(termRows+previewReservedRows)
+		}
+		maxWidth, maxHeight = termCols*cellWidth, termRows*cellHeight
+	}
+	width, height := fitSize(*rf.width, *rf.height, maxWidth, maxHeight)
+
+	// The zoom factor is relative to the image's size, so the smaller image shows the same region
+	gen := lib.NewGenerator(width, height, *rf.routines, *rf.iterations, *rf.samples, *rf.xCentre, -*rf.yCentre, *rf.zoom, pointFunc)
+	rf.transform(&gen)
+	gen.Generate()
+
+	out := bufio.NewWriter(os.Stdout)
+	if *sixel {
+		writeSixel(out, gen.Img)
+	} else {
+		writeHalfBlocks(out, gen.Img)
+	}
+	fmt.Fprintln(out, commandLine(fs, "sixel", "cols", "rows"))
+	out.Flush()
+}
+
+// fitSize scales width by height down to fit into maxWidth by maxHeight, keeping its aspect ratio
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	scale := math.Min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	return int(math.Max(math.Round(float64(width)*scale), 1)), int(math.Max(math.Round(float64(height)*scale), 1))
+}
+
+// writeHalfBlocks writes an image with upper half block characters, coloured
+// with 24 bit ANSI escape codes: each character is the pixel above in the
+// foreground and the pixel below in the background, and the rest of each line
+// is cleared. Transparent pixels are drawn on black.
+func writeHalfBlocks(w io.Writer, img image.Image) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		var last [2][3]uint8
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top := opaqueRGB(img, x, y)
+			if x == b.Min.X || top != last[0] {
+				fmt.Fprintf(w, "\x1b[38;2;%d;%d;%dm", top[0], top[1], top[2])
+			}
+			if y+1 < b.Max.Y {
+				bottom := opaqueRGB(img, x, y+1)
+				if x == b.Min.X || bottom != last[1] {
+					fmt.Fprintf(w, "\x1b[48;2;%d;%d;%dm", bottom[0], bottom[1], bottom[2])
+				}
+				last[1] = bottom
+			}
+			last[0] = top
+			io.WriteString(w, "▀")
+		}
+		io.WriteString(w, "\x1b[0m\x1b[K\n")
+	}
+}
+
+// writeSixel writes an image as a sixel image, with its colours rounded to a
+// 6x6x6 colour cube. Transparent pixels are drawn on black.
+func writeSixel(w io.Writer, img image.Image) {
+	b := img.Bounds()
+	fmt.Fprintf(w, "\x1bPq\"1;1;%d;%d", b.Dx(), b.Dy())
+	for index := 0; index < 216; index++ {
+		fmt.Fprintf(w, "#%d;2;%d;%d;%d", index, index/36*20, index/6%6*20, index%6*20)
+	}
+
+	indices := make([]int, b.Dx()*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			rgb := opaqueRGB(img, b.Min.X+x, b.Min.Y+y)
+			indices[y*b.Dx()+x] = (int(rgb[0])+25)/51*36 + (int(rgb[1])+25)/51*6 + (int(rgb[2])+25)/51
+		}
+	}
+
+	// Each band of six rows is drawn a colour at a time, going back to the start of the band between colours
+	sixels := make([]byte, b.Dx())
+	for band := 0; band < b.Dy(); band += 6 {
+		used := map[int]bool{}
+		for key := band * b.Dx(); key < (band+6)*b.Dx() && key < len(indices); key++ {
+			used[indices[key]] = true
+		}
+		colours := make([]int, 0, len(used))
+		for index := range used {
+			colours = append(colours, index)
+		}
+		sort.Ints(colours)
+
+		for key, index := range colours {
+			for x := range sixels {
+				sixels[x] = 0
+				for row := 0; row < 6 && band+row < b.Dy(); row++ {
+					if indices[(band+row)*b.Dx()+x] == index {
+						sixels[x] |= 1 << uint(row)
+					}
+				}
+			}
+			if key > 0 {
+				io.WriteString(w, "$")
+			}
+			fmt.Fprintf(w, "#%d", index)
+			writeSixelRuns(w, sixels)
+		}
+		io.WriteString(w, "-")
+	}
+	io.WriteString(w, "\x1b\\\n")
+}
+
+// writeSixelRuns writes a row of sixels, run length encoding repeats
+func writeSixelRuns(w io.Writer, sixels []byte) {
+	for x := 0; x < len(sixels); {
+		run := 1
+		for x+run < len(sixels) && sixels[x+run] == sixels[x] {
+			run++
+		}
+		if run > 3 {
+			fmt.Fprintf(w, "!%d%c", run, 63+sixels[x])
+		} else {
+			io.WriteString(w, strings.Repeat(string(rune(63+sixels[x])), run))
+		}
+		x += run
+	}
+}
+
+// opaqueRGB returns the colour of a pixel over black
+func opaqueRGB(img image.Image, x, y int) [3]uint8 {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)}
+}
+
+// commandLine returns the romanesgo command line that sets the flags that are
+// set, leaving out those skipped. Repeatable flags are repeated.
+func commandLine(fs *flag.FlagSet, skip ...string) string {
+	args := []string{"romanesgo"}
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range skip {
+			if f.Name == name {
+				return
+			}
+		}
+		var values []string
+		switch value := f.Value.(type) {
+		case *flagConstants:
+			for _, constant := range *value {
+				values = append(values, strconv.FormatFloat(constant, 'f', -1, 64))
+			}
+		case *flagStrings:
+			values = *value
+		case *flagParams:
+			for name, param := range *value {
+				values = append(values, name+"="+param)
+			}
+			sort.Strings(values)
+		default:
+			// Flags set back to nothing, as the explorer does, are left out
+			if value.String() != "" {
+				values = []string{value.String()}
+			}
+		}
+		for _, value := range values {
+			args = append(args, "-"+f.Name+"="+shellQuote(value))
+		}
+	})
+	return strings.Join(args, " ")
+}
+
+// shellSafe matches strings that don't need quoting in a shell
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./^-]+$`)
+
+// shellQuote quotes a string for a shell, if it needs quoting
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
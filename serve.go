@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/theteacat/romanesgo/server"
+)
+
+// serve serves tiles of the fractals over HTTP, a viewer for exploring them
+// in a browser, and an API for submitting full renders, e.g.
+//
+//	romanesgo serve -addr=localhost:8080
+//
+// then open http://localhost:8080, fetch tiles such as
+// http://localhost:8080/tiles/mandelbrot/2/1/1.png?cf=smoothcolor&i=256, or
+// POST a render to http://localhost:8080/renders
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	cacheSize := fs.Int("cache", 1024, "number of tiles kept in memory")
+	renders := fs.Int("renders", runtime.NumCPU(), "number of tiles rendered at a time")
+	routines := fs.Int("r", 1, "goroutines used for each tile")
+	iterations := fs.Int("i", 128, "maximum iterations, unless a tile asks for its own")
+	workers := fs.Int("workers", 1, "number of full renders rendered at a time")
+	queueSize := fs.Int("queue", 16, "number of full renders that can wait in the queue")
+	renderRoutines := fs.Int("rr", runtime.NumCPU(), "goroutines used for each full render")
+	maxPixels := fs.Int("maxpixels", 64000000, "maximum pixels in a full render")
+	fs.Parse(args)
+
+	fmt.Print("\n\tAddress (addr):\t\t", *addr,
+		"\n\tCache size (cache):\t", *cacheSize,
+		"\n\tRenders (renders):\t", *renders,
+		"\n\tRoutines (r):\t\t", *routines,
+		"\n\tIterations (i):\t\t", *iterations,
+		"\n\tWorkers (workers):\t", *workers,
+		"\n\tQueue size (queue):\t", *queueSize,
+		"\n\tRender routines (rr):\t", *renderRoutines,
+		"\n\tMax pixels (maxpixels):\t", *maxPixels, "\n\n")
+
+	renderServer := server.NewRenderServer(*workers, *queueSize, *renderRoutines, *maxPixels)
+	mux := http.NewServeMux()
+	mux.Handle("/", server.NewTileServer(*cacheSize, *renders, *routines, *iterations))
+	mux.Handle("/renders", renderServer)
+	mux.Handle("/renders/", renderServer)
+	fatal(http.ListenAndServe(*addr, mux))
+}
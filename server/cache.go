@@ -0,0 +1,97 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+)
+
+// tileCache is a least recently used cache of encoded tiles
+type tileCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // most recently used at the front
+	items    map[string]*list.Element
+	renders  map[string]*tileRender // tiles being rendered, by key
+}
+
+// tileRender is a tile being rendered, which requests for it wait on
+type tileRender struct {
+	done chan struct{}
+	png  []byte
+	err  error
+}
+
+type cachedTile struct {
+	key string
+	png []byte
+}
+
+func newTileCache(capacity int) *tileCache {
+	return &tileCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[string]*list.Element{},
+		renders:  map[string]*tileRender{},
+	}
+}
+
+// do returns a tile from the cache, or renders and caches it if it isn't
+// there. Requests for a tile that's already being rendered wait for it,
+// rather than rendering it again.
+func (c *tileCache) do(key string, render func() ([]byte, error)) ([]byte, error) {
+	if png, cached := c.get(key); cached {
+		return png, nil
+	}
+
+	c.mu.Lock()
+	if pending, rendering := c.renders[key]; rendering {
+		c.mu.Unlock()
+		<-pending.done
+		return pending.png, pending.err
+	}
+	pending := &tileRender{done: make(chan struct{})}
+	c.renders[key] = pending
+	c.mu.Unlock()
+
+	pending.png, pending.err = render()
+	if pending.err == nil {
+		c.add(key, pending.png)
+	}
+	c.mu.Lock()
+	delete(c.renders, key)
+	c.mu.Unlock()
+	close(pending.done)
+	return pending.png, pending.err
+}
+
+// get returns a tile if it's cached, marking it as recently used
+func (c *tileCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+	c.order.MoveToFront(item)
+	return item.Value.(cachedTile).png, true
+}
+
+// add caches a tile, evicting the least recently used tiles if it's full
+func (c *tileCache) add(key string, png []byte) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item, exists := c.items[key]; exists {
+		item.Value = cachedTile{key, png}
+		c.order.MoveToFront(item)
+		return
+	}
+	c.items[key] = c.order.PushFront(cachedTile{key, png})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(cachedTile).key)
+	}
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// A big render can be split into regions, rendered by workers in other
+// processes, perhaps on other machines, and put back together by a
+// coordinator. Each worker renders its regions with its own generator, in
+// exactly the same way as if the whole image were rendered in one go.
+
+// RegionSize is the width and height of the regions a coordinator splits renders into
+const RegionSize = 512
+
+// regionSlots is how many regions a coordinator asks each worker for at a
+// time, so workers aren't left idle waiting for their next region
+const regionSlots = 2
+
+// maxWorkerFailures is how many times in a row a worker can fail before a
+// coordinator stops giving it regions
+const maxWorkerFailures = 3
+
+// regionTimeout is how long a coordinator waits for a worker to render a region
+const regionTimeout = 10 * time.Minute
+
+// workerBackoff is how long a coordinator waits before giving a worker that
+// failed another region, times the failures in a row. It's a var so tests can
+// shorten it.
+var workerBackoff = time.Second
+
+// Errors for distributed renders
+var (
+	ErrInvalidRegion = errors.New("invalid region")
+	ErrNoWorkers     = errors.New("every worker failed")
+)
+
+// RegionRequest is a region of a render, as [x0, y0, x1, y1] in pixels, that a
+// coordinator asks a worker for
+type RegionRequest struct {
+	Spec   RenderSpec `json:"spec"`
+	Region [4]int     `json:"region"`
+}
+
+// Worker renders regions of renders for a coordinator. A RegionRequest is
+// POSTed as json to /regions, which returns the region as a png.
+type Worker struct {
+	routines  int
+	maxPixels int
+}
+
+// NewWorker returns a worker that renders regions of at most maxPixels pixels
+// with the given number of goroutines
+func NewWorker(routines, maxPixels int) *Worker {
+	return &Worker{routines, maxPixels}
+}
+
+func (wk *Worker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/regions" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("regions are requested with POST"))
+		return
+	}
+
+	var req RegionRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSpecSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	region := image.Rect(req.Region[0], req.Region[1], req.Region[2], req.Region[3])
+	width, height, err := req.Spec.Size()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if region.Empty() || !region.In(image.Rect(0, 0, width, height)) || region.Dx()*region.Dy() > wk.maxPixels {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: it must be inside the image, with from 1 to %d pixels", ErrInvalidRegion, wk.maxPixels))
+		return
+	}
+
+	gen, err := req.Spec.RegionGenerator(wk.routines, region)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	gen.Generate()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gen.Img); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+// Coordinator splits renders into regions, has workers render them, and puts
+// them back together. If a worker fails, its regions go to the others.
+type Coordinator struct {
+	workers []string
+	client  *http.Client
+
+	// Logf, if it's set, is told when a worker fails
+	Logf func(format string, args ...interface{})
+}
+
+// NewCoordinator returns a coordinator for the workers at the given
+// addresses, e.g. "localhost:9001" or "http://10.0.0.2:9001"
+func NewCoordinator(workers []string) *Coordinator {
+	urls := make([]string, len(workers))
+	for key, worker := range workers {
+		if !strings.Contains(worker, "://") {
+			worker = "http://" + worker
+		}
+		urls[key] = strings.TrimSuffix(worker, "/") + "/regions"
+	}
+	return &Coordinator{workers: urls, client: &http.Client{Timeout: regionTimeout}}
+}
+
+// Render renders the spec across the workers, returning its image once every region is done
+func (c *Coordinator) Render(spec RenderSpec) (*image.NRGBA, error) {
+	width, height, err := spec.Size()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := spec.pointFunc(); err != nil {
+		return nil, err
+	}
+	if len(c.workers) == 0 {
+		return nil, ErrNoWorkers
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	// Regions that fail are put back, so there's room for all of them
+	regions := make(chan image.Rectangle, (width/RegionSize+1)*(height/RegionSize+1))
+	for y := 0; y < height; y += RegionSize {
+		for x := 0; x < width; x += RegionSize {
+			regions <- image.Rect(x, y, x+RegionSize, y+RegionSize).Intersect(img.Rect)
+		}
+	}
+
+	/* Every slot stops once the render is over, one way or the other, so
+	   none are left waiting on regions, and requests still going are
+	   cancelled.
+	*/
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	remaining, live := int64(len(regions)), int64(len(c.workers)*regionSlots)
+	done, failed := make(chan struct{}), make(chan error, 1)
+	for _, worker := range c.workers {
+		for slot := 0; slot < regionSlots; slot++ {
+			go func(worker string) {
+				failures := 0
+				for {
+					var region image.Rectangle
+					select {
+					case <-ctx.Done():
+						return
+					case region = <-regions:
+					}
+
+					err := c.renderRegion(ctx, worker, spec, region, img)
+					if err != nil {
+						regions <- region
+						if ctx.Err() != nil {
+							return
+						}
+						c.logf("%s failed: %v", worker, err)
+						if failures++; failures == maxWorkerFailures {
+							if atomic.AddInt64(&live, -1) == 0 {
+								failed <- fmt.Errorf("%w, the last with: %v", ErrNoWorkers, err)
+							}
+							return
+						}
+						select {
+						case <-ctx.Done():
+							return
+						case <-time.After(time.Duration(failures) * workerBackoff):
+						}
+						continue
+					}
+
+					failures = 0
+					if atomic.AddInt64(&remaining, -1) == 0 {
+						close(done)
+					}
+				}
+			}(worker)
+		}
+	}
+
+	select {
+	case <-done:
+		return img, nil
+	case err := <-failed:
+		return nil, err
+	}
+}
+
+// renderRegion has a worker render a region, and copies it into the image
+func (c *Coordinator) renderRegion(ctx context.Context, worker string, spec RenderSpec, region image.Rectangle, img *image.NRGBA) error {
+	body, err := json.Marshal(RegionRequest{spec, [4]int{region.Min.X, region.Min.Y, region.Max.X, region.Max.Y}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, worker, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	tile, err := png.Decode(resp.Body)
+	if err != nil {
+		return err
+	}
+	/* Opaque pngs are decoded as RGBA, which is then the same as NRGBA, as
+	   premultiplying by an alpha of 255 changes nothing.
+	*/
+	var pix []uint8
+	var stride int
+	switch tile := tile.(type) {
+	case *image.NRGBA:
+		pix, stride = tile.Pix, tile.Stride
+	case *image.RGBA:
+		pix, stride = tile.Pix, tile.Stride
+	}
+	if pix == nil || tile.Bounds().Size() != region.Size() {
+		return fmt.Errorf("%w: the worker sent back an image of the wrong size or format", ErrInvalidRegion)
+	}
+
+	/* Regions don't overlap, so they're copied in row by row without a lock,
+	   and without the rounding draw.Draw would do going through premultiplied
+	   alpha.
+	*/
+	rowLength := region.Dx() * 4
+	for row := 0; row < region.Dy(); row++ {
+		copy(img.Pix[img.PixOffset(region.Min.X, region.Min.Y+row):][:rowLength], pix[row*stride:][:rowLength])
+	}
+	return nil
+}
+
+func (c *Coordinator) logf(format string, args ...interface{}) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSpec returns a spec big enough to be split into six regions, more than
+// two workers' slots can take at once
+func testSpec() RenderSpec {
+	spec := DefaultRenderSpec()
+	spec.Fractal = "mandelbrot"
+	spec.Color = "smoothcolor"
+	spec.Iterations = 64
+	spec.X = -0.5
+	spec.Width, spec.Height = 3*RegionSize-100, 2*RegionSize-50
+	return spec
+}
+
+// failingWorker returns a server that fails every region, counting them
+func failingWorker(requests *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(requests, 1)
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+}
+
+func TestCoordinatorRender(t *testing.T) {
+	defer func(backoff time.Duration) { workerBackoff = backoff }(workerBackoff)
+	workerBackoff = time.Millisecond
+
+	/* The good workers wait for the failing one to be asked for a region,
+	   which it will be, as their slots can only hold four of the six.
+	*/
+	var failures int64
+	waitForFailure := func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for wait := 0; atomic.LoadInt64(&failures) == 0 && wait < 500; wait++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+	workers := []*httptest.Server{
+		httptest.NewServer(waitForFailure(NewWorker(2, 1<<20))),
+		httptest.NewServer(waitForFailure(NewWorker(2, 1<<20))),
+		failingWorker(&failures),
+	}
+	addrs := []string{}
+	for _, worker := range workers {
+		defer worker.Close()
+		addrs = append(addrs, worker.URL)
+	}
+
+	spec := testSpec()
+	img, err := NewCoordinator(addrs).Render(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen, err := spec.Generator(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen.Generate()
+	if img.Rect != gen.Img.Rect || !bytes.Equal(img.Pix, gen.Img.Pix) {
+		t.Error("the distributed render isn't the same as rendering it in one process")
+	}
+	if atomic.LoadInt64(&failures) == 0 {
+		t.Error("the failing worker wasn't given any regions")
+	}
+}
+
+func TestCoordinatorRenderFails(t *testing.T) {
+	defer func(backoff time.Duration) { workerBackoff = backoff }(workerBackoff)
+	workerBackoff = time.Millisecond
+
+	var requests int64
+	worker := failingWorker(&requests)
+	defer worker.Close()
+
+	coordinator := NewCoordinator([]string{worker.URL})
+	goroutines := runtime.NumGoroutine()
+	_, err := coordinator.Render(testSpec())
+	if !errors.Is(err, ErrNoWorkers) {
+		t.Fatalf("Render returned %v, want %v", err, ErrNoWorkers)
+	}
+	if got, want := atomic.LoadInt64(&requests), int64(regionSlots*maxWorkerFailures); got != want {
+		t.Errorf("the worker was asked for %d regions, want %d", got, want)
+	}
+
+	// The slots and connections should all wind down
+	coordinator.client.CloseIdleConnections()
+	for wait := 0; runtime.NumGoroutine() > goroutines && wait < 100; wait++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runtime.NumGoroutine() > goroutines {
+		t.Errorf("%d goroutines are left over from the render", runtime.NumGoroutine()-goroutines)
+	}
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"image/png"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned when a render is submitted while the queue is full
+var ErrQueueFull = errors.New("the render queue is full, try again later")
+
+// keepRenders is how many finished renders are kept, with their images,
+// before the oldest are forgotten
+const keepRenders = 64
+
+// keepRenderBytes caps the total size of the images of the finished renders
+// kept, though the latest is always kept whatever its size
+var keepRenderBytes = 256 << 20
+
+// maxSpecSize caps the size of a spec in bytes
+const maxSpecSize = 1 << 20
+
+// The states of a render
+const (
+	renderQueued  = "queued"
+	renderRunning = "running"
+	renderDone    = "done"
+	renderFailed  = "failed"
+)
+
+// RenderServer renders specs submitted over HTTP, queueing them up for a
+// fixed number of workers. A spec is POSTed as json to /renders, which
+// returns the render's status, with its id, and the render's status and
+// progress are at /renders/{id} and its image, once it's done, at
+// /renders/{id}/image, e.g.
+//
+//	curl -d '{"ff": "julia", "c": [-0.8, 0.156], "w": 4000, "h": 3000}' localhost:8080/renders
+type RenderServer struct {
+	routines  int
+	maxPixels int
+	queue     chan *renderJob
+
+	mu       sync.Mutex
+	renders  map[string]*renderJob
+	finished []string // ids of finished renders, oldest first
+	kept     int      // total size of their images
+}
+
+// renderJob is a submitted render and how it's getting on
+type renderJob struct {
+	id       string
+	spec     RenderSpec
+	state    string
+	err      error
+	progress func() float64
+	image    []byte
+	queued   time.Time
+	started  time.Time
+	finished time.Time
+}
+
+// renderStatus is a render's status as it's returned in json
+type renderStatus struct {
+	ID       string     `json:"id"`
+	State    string     `json:"state"`
+	Progress float64    `json:"progress"`
+	Error    string     `json:"error,omitempty"`
+	Image    string     `json:"image,omitempty"`
+	Queued   time.Time  `json:"queued"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Spec     RenderSpec `json:"spec"`
+}
+
+// NewRenderServer returns a render server with the given number of workers,
+// each rendering with the given number of goroutines, that queues at most
+// queueSize renders and renders at most maxPixels pixels each, which between
+// them bound the CPU and memory renders can take.
+func NewRenderServer(workers, queueSize, routines, maxPixels int) *RenderServer {
+	s := &RenderServer{
+		routines:  routines,
+		maxPixels: maxPixels,
+		queue:     make(chan *renderJob, queueSize),
+		renders:   map[string]*renderJob{},
+	}
+	for worker := 0; worker < workers; worker++ {
+		go s.work()
+	}
+	return s
+}
+
+func (s *RenderServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/renders"), "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "" && r.Method == http.MethodPost:
+		s.submit(w, r)
+	case path == "":
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("renders are submitted with POST"))
+	case len(parts) == 1:
+		s.status(w, parts[0])
+	case len(parts) == 2 && parts[1] == "image":
+		s.image(w, parts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// submit queues up a render of the posted spec, if the spec is valid and there's room
+func (s *RenderServer) submit(w http.ResponseWriter, r *http.Request) {
+	spec := DefaultRenderSpec()
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSpecSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := spec.Check(s.maxPixels); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job := &renderJob{id: newID(), spec: spec, state: renderQueued, queued: time.Now()}
+	s.mu.Lock()
+	select {
+	case s.queue <- job:
+		s.renders[job.id] = job
+	default:
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "10")
+		writeError(w, http.StatusServiceUnavailable, ErrQueueFull)
+		return
+	}
+	status := job.status()
+	s.mu.Unlock()
+
+	w.Header().Set("Location", "/renders/"+job.id)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// status returns a render's status
+func (s *RenderServer) status(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	job, exists := s.renders[id]
+	var status renderStatus
+	if exists {
+		status = job.status()
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		writeError(w, http.StatusNotFound, errors.New("no such render"))
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// image returns a render's image, if it's done
+func (s *RenderServer) image(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	job, exists := s.renders[id]
+	var state string
+	var image []byte
+	if exists {
+		state, image = job.state, job.image
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !exists:
+		writeError(w, http.StatusNotFound, errors.New("no such render"))
+	case state != renderDone:
+		writeError(w, http.StatusConflict, errors.New("the render is "+state))
+	default:
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(image)
+	}
+}
+
+// work renders queued renders one after another
+func (s *RenderServer) work() {
+	for job := range s.queue {
+		gen, err := job.spec.Generator(s.routines)
+
+		s.mu.Lock()
+		job.state, job.started = renderRunning, time.Now()
+		if err == nil {
+			job.progress = gen.Progress
+		}
+		s.mu.Unlock()
+
+		var image []byte
+		if err == nil {
+			gen.Generate()
+			var buf bytes.Buffer
+			err = png.Encode(&buf, gen.Img)
+			image = buf.Bytes()
+		}
+
+		s.mu.Lock()
+		job.finished, job.progress = time.Now(), nil
+		if err != nil {
+			job.state, job.err = renderFailed, err
+		} else {
+			job.state, job.image = renderDone, image
+		}
+		s.finished = append(s.finished, job.id)
+		s.kept += len(job.image)
+		for len(s.finished) > keepRenders || (s.kept > keepRenderBytes && len(s.finished) > 1) {
+			s.kept -= len(s.renders[s.finished[0]].image)
+			delete(s.renders, s.finished[0])
+			s.finished = s.finished[1:]
+		}
+		s.mu.Unlock()
+	}
+}
+
+// status returns the render's status, which needs the server's lock
+func (job *renderJob) status() renderStatus {
+	status := renderStatus{ID: job.id, State: job.state, Queued: job.queued, Spec: job.spec}
+	started, finished := job.started, job.finished
+	switch job.state {
+	case renderRunning:
+		if job.progress != nil {
+			status.Progress = job.progress()
+		}
+		status.Started = &started
+	case renderDone, renderFailed:
+		status.Started, status.Finished = &started, &finished
+		if job.err != nil {
+			status.Error = job.err.Error()
+		} else {
+			status.Progress, status.Image = 1, "/renders/"+job.id+"/image"
+		}
+	}
+	return status
+}
+
+// newID returns a random id for a render
+func newID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes an error as {"error": "..."}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// submit posts a spec to the server
+func submit(s *RenderServer, spec string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/renders", strings.NewReader(spec)))
+	return w
+}
+
+// get gets a path from the server
+func get(s *RenderServer, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+// render submits a spec and waits for it to be rendered, returning its status
+func render(t *testing.T, s *RenderServer, spec string) renderStatus {
+	t.Helper()
+	w := submit(s, spec)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	var status renderStatus
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if location := w.Header().Get("Location"); location != "/renders/"+status.ID {
+		t.Errorf("Location is %q, want /renders/%s", location, status.ID)
+	}
+
+	for wait := 0; status.State != renderDone; wait++ {
+		if status.State == renderFailed || wait == 500 {
+			t.Fatalf("the render is %s: %s", status.State, status.Error)
+		}
+		time.Sleep(10 * time.Millisecond)
+		w = get(s, "/renders/"+status.ID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("got %d: %s", w.Code, w.Body)
+		}
+		status = renderStatus{}
+		if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return status
+}
+
+func TestRender(t *testing.T) {
+	s := NewRenderServer(1, 4, 2, 1<<20)
+	status := render(t, s, `{"ff": "julia", "c": [-0.8, 0.156], "cf": "smoothcolor", "w": 96, "h": 64}`)
+	if status.Progress != 1 || status.Image != "/renders/"+status.ID+"/image" {
+		t.Errorf("the finished render's status is %+v", status)
+	}
+
+	w := get(s, status.Image)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	decoded, err := png.Decode(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewNRGBA(decoded.Bounds())
+	draw.Draw(img, img.Rect, decoded, image.Point{}, draw.Src)
+
+	gen, err := status.Spec.Generator(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen.Generate()
+	if !bytes.Equal(img.Pix, gen.Img.Pix) {
+		t.Error("the image isn't the same as rendering the spec directly")
+	}
+}
+
+func TestRenderQueueFull(t *testing.T) {
+	// Without workers nothing leaves the queue
+	s := NewRenderServer(0, 1, 1, 1<<20)
+	first := submit(s, `{"ff": "mandelbrot", "w": 10, "h": 10}`)
+	if first.Code != http.StatusAccepted {
+		t.Fatalf("got %d: %s", first.Code, first.Body)
+	}
+	if w := submit(s, `{"ff": "mandelbrot", "w": 10, "h": 10}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got %d, want %d: %s", w.Code, http.StatusServiceUnavailable, w.Body)
+	}
+
+	if w := get(s, first.Header().Get("Location")+"/image"); w.Code != http.StatusConflict {
+		t.Errorf("the image of a queued render got %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestRenderInvalid(t *testing.T) {
+	s := NewRenderServer(0, 1, 1, 1<<20)
+	specs := []string{
+		`{"ff": "nosuchfractal"}`,
+		`{"ff": "mandelbrot", "cf": "nosuchscheme"}`,
+		`{"ff": "mandelbrot", "z": 0}`,
+		`{"ff": "mandelbrot", "z": -2}`,
+		`{"ff": "mandelbrot", "i": 0}`,
+		`{"ff": "mandelbrot", "w": 100000, "h": 100000}`,
+		`{"ff": "mandelbrot", "w": 4294967296, "h": 4294967296}`,
+		`{"ff": "mandelbrot", "w": 0, "h": 100}`,
+		`{"ff": "mandelbrot", "rad": 1, "fit": "nosuchfit"}`,
+		`{"ff": "mandelbrot", "nosuchfield": 1}`,
+		`{"ff": "mandelbrot"`,
+	}
+	for _, spec := range specs {
+		if w := submit(s, spec); w.Code != http.StatusBadRequest {
+			t.Errorf("%s got %d, want %d: %s", spec, w.Code, http.StatusBadRequest, w.Body)
+		}
+	}
+	if w := get(s, "/renders/nosuchrender"); w.Code != http.StatusNotFound {
+		t.Errorf("an unknown render got %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestRenderKeptBytes(t *testing.T) {
+	defer func(bytes int) { keepRenderBytes = bytes }(keepRenderBytes)
+	keepRenderBytes = 1
+
+	// Only the latest render fits, so the first is forgotten once the second is done
+	s := NewRenderServer(1, 4, 1, 1<<20)
+	first := render(t, s, `{"ff": "mandelbrot", "w": 32, "h": 32}`)
+	second := render(t, s, `{"ff": "mandelbrot", "w": 32, "h": 32, "z": 2}`)
+	if w := get(s, first.Image); w.Code != http.StatusNotFound {
+		t.Errorf("the first render's image got %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := get(s, second.Image); w.Code != http.StatusOK {
+		t.Errorf("the second render's image got %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/theteacat/romanesgo/lib"
+)
+
+// ErrInvalidSpec is returned for a render spec that can't be rendered
+var ErrInvalidSpec = errors.New("invalid render spec")
+
+// RenderSpec is a render, with the same fields and defaults as the CLI's
+// flags, named after them in json. Points and regions are given as arrays:
+// "jc" as [x, y], "m" as [a, b, c, d] and "bounds" as [xmin, xmax, ymin, ymax].
+type RenderSpec struct {
+	Fractal        string            `json:"ff"`
+	Formula        string            `json:"fx,omitempty"`
+	FormulaBailout string            `json:"fxb,omitempty"`
+	BailoutRadius  float64           `json:"br,omitempty"`
+	BailoutNorm    string            `json:"bn,omitempty"`
+	Constants      []float64         `json:"c,omitempty"`
+	Strings        []string          `json:"s,omitempty"`
+	Params         map[string]string `json:"p,omitempty"`
+	JuliaPoint     *[2]float64       `json:"jc,omitempty"`
+	Iterations     int               `json:"i"`
+	Color          string            `json:"cf"`
+	PaletteOffset  float64           `json:"po,omitempty"`
+	X              float64           `json:"x"`
+	Y              float64           `json:"y"`
+	Zoom           float64           `json:"z"`
+	Rotation       float64           `json:"rot,omitempty"`
+	Matrix         [4]float64        `json:"m"`
+	Bounds         *[4]float64       `json:"bounds,omitempty"`
+	Radius         float64           `json:"rad,omitempty"`
+	PlaneWidth     float64           `json:"pw,omitempty"`
+	Fit            string            `json:"fit,omitempty"`
+	Width          int               `json:"w"`
+	Height         int               `json:"h"`
+	Samples        int               `json:"ss"`
+}
+
+// DefaultRenderSpec returns a spec with the CLI's defaults, for decoding a
+// spec into so the fields it leaves out keep them
+func DefaultRenderSpec() RenderSpec {
+	return RenderSpec{
+		Iterations: 128,
+		Color:      "default",
+		Zoom:       1,
+		Matrix:     [4]float64{1, 0, 0, 1},
+		Fit:        "expand",
+		Width:      1000,
+		Height:     1000,
+		Samples:    1,
+	}
+}
+
+// Check checks the spec can be rendered, with at most maxPixels pixels,
+// without allocating its image
+func (spec RenderSpec) Check(maxPixels int) error {
+	if spec.Iterations < 1 || spec.Iterations > MaxIterations {
+		return fmt.Errorf("%w: i must be from 1 to %d", ErrInvalidSpec, MaxIterations)
+	}
+	if spec.Samples < 1 || spec.Samples > maxSamples {
+		return fmt.Errorf("%w: ss must be from 1 to %d", ErrInvalidSpec, maxSamples)
+	}
+	view, err := spec.view()
+	if err != nil {
+		return err
+	}
+	// Each side is checked before multiplying them, which could overflow
+	if spec.Width < 1 || view.Height < 1 || spec.Width > maxPixels/view.Height {
+		return fmt.Errorf("%w: the image must have from 1 to %d pixels", ErrInvalidSpec, maxPixels)
+	}
+	_, err = spec.pointFunc()
+	return err
+}
+
+// Size returns the size of the spec's image, which is only worked out from
+// w for some fits
+func (spec RenderSpec) Size() (width, height int, err error) {
+	view, err := spec.view()
+	return spec.Width, view.Height, err
+}
+
+// Generator returns a generator for the spec, using the given number of goroutines
+func (spec RenderSpec) Generator(routines int) (lib.Generator, error) {
+	return spec.generator(routines, nil)
+}
+
+// RegionGenerator returns a generator for just a region of the spec's image,
+// using the given number of goroutines
+func (spec RenderSpec) RegionGenerator(routines int, region image.Rectangle) (lib.Generator, error) {
+	return spec.generator(routines, &region)
+}
+
+// generator returns a generator for the region of the spec's image, or all of it if it's nil
+func (spec RenderSpec) generator(routines int, region *image.Rectangle) (lib.Generator, error) {
+	view, err := spec.view()
+	if err != nil {
+		return lib.Generator{}, err
+	}
+	pointFunc, err := spec.pointFunc()
+	if err != nil {
+		return lib.Generator{}, err
+	}
+
+	var gen lib.Generator
+	if region != nil {
+		gen = lib.NewRegionGenerator(*region, spec.Width, view.Height, routines, spec.Iterations, spec.Samples, view.XPos, view.YPos, view.Zoom, pointFunc)
+	} else {
+		gen = lib.NewGenerator(spec.Width, view.Height, routines, spec.Iterations, spec.Samples, view.XPos, view.YPos, view.Zoom, pointFunc)
+	}
+	gen.SetTransform(spec.Matrix[0], spec.Matrix[1], spec.Matrix[2], spec.Matrix[3])
+	gen.SetView(view.XPos, view.YPos, view.Zoom, spec.Rotation)
+	if view.Clip != nil {
+		gen.SetClip(*view.Clip)
+	}
+	return gen, nil
+}
+
+// pointFunc gets a PointFunc for either the formula or the fractal
+func (spec RenderSpec) pointFunc() (lib.PointFunc, error) {
+	norm, err := lib.GetNorm(spec.BailoutNorm)
+	if err != nil {
+		return nil, err
+	}
+	opts := lib.Options{
+		PaletteOffset: spec.PaletteOffset,
+		Strings:       spec.Strings,
+		Params:        spec.Params,
+		BailoutRadius: spec.BailoutRadius,
+		BailoutNorm:   norm,
+	}
+
+	if spec.Formula != "" {
+		if spec.JuliaPoint != nil {
+			return nil, fmt.Errorf("%w: jc needs a fractal from ff", ErrInvalidSpec)
+		}
+		return lib.GetFormulaPointFunc(spec.Formula, spec.FormulaBailout, spec.Color, spec.Constants, opts)
+	}
+	fractalName, constants := spec.Fractal, spec.Constants
+	if spec.JuliaPoint != nil {
+		if _, named := spec.Params["c"]; named {
+			return nil, fmt.Errorf("%w: jc sets c, so it can't be given in p too", ErrInvalidSpec)
+		}
+		fractalName, constants, err = lib.JuliaConstants(fractalName, constants, spec.JuliaPoint[0], -spec.JuliaPoint[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lib.GetPointFunc(fractalName, spec.Color, constants, opts)
+}
+
+// view works out the centre, zoom factor and image height, from bounds, rad or
+// pw if any of them were given, in the same way as the CLI
+func (spec RenderSpec) view() (lib.View, error) {
+	framing := lib.Framing{Radius: spec.Radius, PlaneWidth: spec.PlaneWidth, Fit: spec.Fit}
+	if spec.Bounds != nil {
+		framing.Bounds = &lib.Bounds{XMin: spec.Bounds[0], XMax: spec.Bounds[1], YMin: -spec.Bounds[3], YMax: -spec.Bounds[2]}
+	}
+	return framing.Frame(spec.Width, spec.Height, spec.X, -spec.Y, spec.Zoom)
+}
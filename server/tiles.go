@@ -0,0 +1,254 @@
+// Package server serves fractals over HTTP, as tiles for exploring them in a
+// browser and as full renders submitted through a json API.
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/theteacat/romanesgo/lib"
+)
+
+// TileSize is the width and height of every tile, in pixels
+const TileSize = 256
+
+// MaxTileZoom is the deepest zoom level served, past which float64s run out of precision
+const MaxTileZoom = 40
+
+// worldSize is the width and height of the region of the complex plane covered by the tile at zoom level 0
+const worldSize = 4
+
+// MaxIterations caps the iterations a request can ask for
+const MaxIterations = 100000
+
+// maxSamples caps the supersampling factor a request can ask for
+const maxSamples = 4
+
+// Errors for tile requests
+var (
+	ErrInvalidTile  = errors.New("invalid tile, expected /tiles/{fractal}/{z}/{x}/{y}.png")
+	ErrInvalidQuery = errors.New("invalid query parameter")
+)
+
+// TileServer serves tiles of fractals in XYZ addressing, at
+// /tiles/{fractal}/{z}/{x}/{y}.png, and a viewer for exploring them at /.
+// The tile at zoom level 0 covers the square from -2-2i to 2+2i, and each
+// level splits every tile into four. The color scheme, constants and so on
+// are given as query parameters, named after the CLI's flags, e.g.
+//
+//	/tiles/julia/3/2/5.png?cf=smoothcolor&c=-0.2&c=0.65&i=256
+type TileServer struct {
+	routines   int
+	iterations int
+	cache      *tileCache
+	renders    chan struct{}
+}
+
+// NewTileServer returns a tile server that caches the given number of tiles,
+// renders at most renders tiles at a time, and renders each tile with the
+// given number of goroutines and, unless the request gives its own, iterations.
+func NewTileServer(cacheSize, renders, routines, iterations int) *TileServer {
+	return &TileServer{
+		routines:   routines,
+		iterations: iterations,
+		cache:      newTileCache(cacheSize),
+		renders:    make(chan struct{}, renders),
+	}
+}
+
+func (s *TileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/":
+		serveViewer(w, r)
+	case strings.HasPrefix(r.URL.Path, "/tiles/"):
+		s.serveTile(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveTile serves a tile from the cache, rendering it first if it isn't there
+func (s *TileServer) serveTile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "tiles can only be got", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tile, err := s.tile(r.URL)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, lib.ErrInvalidFractal) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(tile)
+}
+
+// tile returns the encoded tile for a url, from the cache if it's there
+func (s *TileServer) tile(u *url.URL) ([]byte, error) {
+	fractalName, z, x, y, err := parseTilePath(u.Path)
+	if err != nil {
+		return nil, err
+	}
+	q, err := parseQuery(u.Query(), s.iterations)
+	if err != nil {
+		return nil, err
+	}
+	frac, err := lib.GetFractal(fractalName)
+	if err != nil {
+		return nil, err
+	}
+	pointFunc, err := lib.GetPointFunc(fractalName, q.colorName, q.constants, q.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	/* The key is made from what was parsed, with the names as they're looked
+	   up, so however the request is written the same tile has the same key.
+	*/
+	fractalName = strings.ToLower(fractalName)
+	if q.colorName == "" || q.colorName == "default" {
+		q.colorName = frac.DefaultColorScheme
+	}
+	key := fmt.Sprintf("%s/%d/%d/%d?%+v", fractalName, z, x, y, q)
+	return s.cache.do(key, func() ([]byte, error) {
+		return s.render(pointFunc, q, z, x, y)
+	})
+}
+
+// render renders and encodes a tile
+func (s *TileServer) render(pointFunc lib.PointFunc, q renderQuery, z, x, y int) ([]byte, error) {
+	s.renders <- struct{}{}
+	defer func() { <-s.renders }()
+
+	xPos, yPos, zoom := lib.FitBounds(TileSize, TileSize, TileBounds(z, x, y))
+	gen := lib.NewGenerator(TileSize, TileSize, s.routines, q.iterations, q.samples, xPos, yPos, zoom, pointFunc)
+	gen.Generate()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gen.Img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// TileBounds returns the region of the complex plane covered by a tile, in
+// the generator's orientation, with y increasing down the tile
+func TileBounds(z, x, y int) lib.Bounds {
+	side := worldSize / math.Exp2(float64(z))
+	return lib.Bounds{
+		XMin: -worldSize/2 + float64(x)*side,
+		XMax: -worldSize/2 + float64(x+1)*side,
+		YMin: -worldSize/2 + float64(y)*side,
+		YMax: -worldSize/2 + float64(y+1)*side,
+	}
+}
+
+// parseTilePath parses /tiles/{fractal}/{z}/{x}/{y}.png
+func parseTilePath(path string) (fractalName string, z, x, y int, err error) {
+	parts := strings.Split(strings.TrimPrefix(path, "/tiles/"), "/")
+	if len(parts) != 4 || !strings.HasSuffix(parts[3], ".png") {
+		return "", 0, 0, 0, ErrInvalidTile
+	}
+	parts[3] = strings.TrimSuffix(parts[3], ".png")
+
+	var coords [3]int
+	for key, part := range parts[1:] {
+		if coords[key], err = strconv.Atoi(part); err != nil {
+			return "", 0, 0, 0, ErrInvalidTile
+		}
+	}
+	z, x, y = coords[0], coords[1], coords[2]
+	if z < 0 || z > MaxTileZoom || x < 0 || y < 0 || x >= 1<<uint(z) || y >= 1<<uint(z) {
+		return "", 0, 0, 0, fmt.Errorf("%w: zoom levels go from 0 to %d, and x and y from 0 to 2^z - 1", ErrInvalidTile, MaxTileZoom)
+	}
+	return parts[0], z, x, y, nil
+}
+
+// renderQuery is everything about a render given by query parameters
+type renderQuery struct {
+	colorName  string
+	constants  []float64
+	iterations int
+	samples    int
+	opts       lib.Options
+}
+
+// parseQuery parses the query parameters of a tile: cf, c, s, p, i, ss, po, br and bn
+func parseQuery(values url.Values, iterations int) (renderQuery, error) {
+	q := renderQuery{
+		colorName:  strings.ToLower(values.Get("cf")),
+		iterations: iterations,
+		samples:    1,
+		opts:       lib.Options{Strings: values["s"], Params: map[string]string{}},
+	}
+
+	for _, str := range values["c"] {
+		constant, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return q, fmt.Errorf("%w: c=%s is not a number", ErrInvalidQuery, str)
+		}
+		q.constants = append(q.constants, constant)
+	}
+	for _, str := range values["p"] {
+		parts := strings.SplitN(str, "=", 2)
+		if len(parts) != 2 {
+			return q, fmt.Errorf(`%w: p=%s is not "name=value"`, ErrInvalidQuery, str)
+		}
+		q.opts.Params[parts[0]] = parts[1]
+	}
+
+	ints := []struct {
+		name     string
+		value    *int
+		min, max int
+	}{
+		{"i", &q.iterations, 1, MaxIterations},
+		{"ss", &q.samples, 1, maxSamples},
+	}
+	for _, param := range ints {
+		if str := values.Get(param.name); str != "" {
+			value, err := strconv.Atoi(str)
+			if err != nil || value < param.min || value > param.max {
+				return q, fmt.Errorf("%w: %s must be a whole number from %d to %d", ErrInvalidQuery, param.name, param.min, param.max)
+			}
+			*param.value = value
+		}
+	}
+
+	floats := []struct {
+		name  string
+		value *float64
+	}{
+		{"po", &q.opts.PaletteOffset},
+		{"br", &q.opts.BailoutRadius},
+	}
+	for _, param := range floats {
+		if str := values.Get(param.name); str != "" {
+			value, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return q, fmt.Errorf("%w: %s=%s is not a number", ErrInvalidQuery, param.name, str)
+			}
+			*param.value = value
+		}
+	}
+
+	norm, err := lib.GetNorm(values.Get("bn"))
+	if err != nil {
+		return q, err
+	}
+	q.opts.BailoutNorm = norm
+	return q, nil
+}
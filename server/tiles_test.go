@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// getTile requests a tile from the server
+func getTile(s *TileServer, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestServeTile(t *testing.T) {
+	s := NewTileServer(16, 2, 2, 64)
+	w := getTile(s, "/tiles/mandelbrot/2/1/1.png?cf=smoothcolor")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type is %q, want image/png", got)
+	}
+	img, err := png.Decode(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != TileSize || size.Y != TileSize {
+		t.Errorf("the tile is %v, want %dx%d", size, TileSize, TileSize)
+	}
+}
+
+func TestServeTileErrors(t *testing.T) {
+	s := NewTileServer(16, 2, 2, 64)
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/tiles/mandelbrot/2/1.png", http.StatusBadRequest},
+		{"/tiles/mandelbrot/2/1/1.jpg", http.StatusBadRequest},
+		{"/tiles/mandelbrot/a/1/1.png", http.StatusBadRequest},
+		{"/tiles/mandelbrot/-1/0/0.png", http.StatusBadRequest},
+		{"/tiles/mandelbrot/41/0/0.png", http.StatusBadRequest},
+		{"/tiles/mandelbrot/2/4/0.png", http.StatusBadRequest},
+		{"/tiles/mandelbrot/2/0/4.png", http.StatusBadRequest},
+		{"/tiles/mandelbrot/2/-1/0.png", http.StatusBadRequest},
+		{"/tiles/mandelbrot/0/0/0.png?i=0", http.StatusBadRequest},
+		{"/tiles/mandelbrot/0/0/0.png?c=x", http.StatusBadRequest},
+		{"/tiles/mandelbrot/0/0/0.png?cf=nosuchscheme", http.StatusBadRequest},
+		{"/tiles/nosuchfractal/0/0/0.png", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		if w := getTile(s, test.path); w.Code != test.status {
+			t.Errorf("%s got %d, want %d: %s", test.path, w.Code, test.status, w.Body)
+		}
+	}
+}
+
+func TestServeTileCached(t *testing.T) {
+	s := NewTileServer(16, 2, 2, 64)
+	first := getTile(s, "/tiles/mandelbrot/1/0/1.png?i=128&cf=smoothcolor")
+	if first.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", first.Code, first.Body)
+	}
+	if len(s.cache.items) != 1 {
+		t.Fatalf("%d tiles are cached, want 1", len(s.cache.items))
+	}
+
+	// Swap the cached tile for one that can be told apart from a fresh render
+	for key := range s.cache.items {
+		s.cache.add(key, []byte("cached"))
+	}
+	for _, path := range []string{
+		"/tiles/mandelbrot/1/0/1.png?i=128&cf=smoothcolor",
+		"/tiles/mandelbrot/1/0/1.png?cf=smoothcolor&i=128",
+		"/tiles/mandelbrot/1/0/1.png?cf=SmoothColor&i=0128",
+		"/tiles/Mandelbrot/1/0/1.png?i=128&cf=smoothcolor",
+	} {
+		if w := getTile(s, path); w.Body.String() != "cached" {
+			t.Errorf("%s wasn't served from the cache", path)
+		}
+	}
+}
+
+func TestServeTileDefaultColorCached(t *testing.T) {
+	s := NewTileServer(16, 2, 2, 64)
+	for _, path := range []string{
+		"/tiles/julia/0/0/0.png",
+		"/tiles/julia/0/0/0.png?cf=default",
+		"/tiles/julia/0/0/0.png?cf=simplegrayscale",
+	} {
+		if w := getTile(s, path); w.Code != http.StatusOK {
+			t.Fatalf("%s got %d: %s", path, w.Code, w.Body)
+		}
+	}
+	if len(s.cache.items) != 1 {
+		t.Errorf("%d tiles are cached for the default colour scheme, want 1", len(s.cache.items))
+	}
+}
+
+func TestTileCacheSharesRenders(t *testing.T) {
+	c := newTileCache(16)
+	var renders int64
+	render := func() ([]byte, error) {
+		atomic.AddInt64(&renders, 1)
+		time.Sleep(50 * time.Millisecond)
+		return []byte("tile"), nil
+	}
+
+	var wg sync.WaitGroup
+	for request := 0; request < 8; request++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if png, err := c.do("key", render); err != nil || !bytes.Equal(png, []byte("tile")) {
+				t.Errorf("got %q, %v", png, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if renders != 1 {
+		t.Errorf("the tile was rendered %d times, want 1", renders)
+	}
+}
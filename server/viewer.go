@@ -0,0 +1,182 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+
+	"github.com/theteacat/romanesgo/lib"
+)
+
+// serveViewer serves a page for exploring the tiles, which needs nothing but
+// the server, so it works offline. It's passed the fractals and their color
+// schemes, so they can be picked from lists.
+func serveViewer(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(lib.Fractals))
+	for name := range lib.Fractals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schemes := map[string][]string{}
+	for _, name := range names {
+		schemes[name] = lib.Fractals[name].ColorSchemes
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	viewer.Execute(w, struct {
+		Names    []string
+		Schemes  map[string][]string
+		TileSize int
+		MaxZoom  int
+	}{names, schemes, TileSize, MaxTileZoom})
+}
+
+// viewer is a slippy map of the tiles. Dragging pans it, and the wheel zooms
+// it in and out around the cursor. Its state is kept in the url's hash, so
+// views can be bookmarked, and the CLI command for the view is shown below it.
+// It's written here rather than vendored from a map library, which would be
+// far bigger than the panning and zooming it needs, and would have to be kept
+// in a string too, as the module predates go:embed.
+var viewer = template.Must(template.New("viewer").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>romanesgo</title>
+<style>
+	html, body { margin: 0; height: 100%; font: 13px sans-serif; background: #111; color: #ddd; }
+	#map { position: absolute; top: 0; bottom: 0; left: 0; right: 0; overflow: hidden; cursor: grab; }
+	#map img { position: absolute; width: {{.TileSize}}px; height: {{.TileSize}}px; user-select: none; -webkit-user-drag: none; }
+	#controls { position: absolute; top: 8px; left: 8px; padding: 8px; background: rgba(0, 0, 0, 0.7); border-radius: 4px; }
+	#controls label { margin-right: 8px; }
+	#command { position: absolute; bottom: 8px; left: 8px; right: 8px; padding: 6px; background: rgba(0, 0, 0, 0.7); font-family: monospace; user-select: all; }
+</style>
+</head>
+<body>
+<div id="map"></div>
+<div id="controls">
+	<label>Fractal <select id="ff">{{range .Names}}<option>{{.}}</option>{{end}}</select></label>
+	<label>Color <select id="cf"></select></label>
+	<label>Iterations <input id="i" type="number" min="1" value="128" style="width: 6em"></label>
+	<label>Constants <input id="c" placeholder="e.g. -0.2, 0.65" style="width: 10em"></label>
+	<label>Params <input id="p" placeholder="e.g. power=3" style="width: 10em"></label>
+</div>
+<div id="command"></div>
+<script>
+var schemes = {{.Schemes}}, tileSize = {{.TileSize}}, maxZoom = {{.MaxZoom}};
+var map = document.getElementById("map"), command = document.getElementById("command");
+var inputs = {ff: document.getElementById("ff"), cf: document.getElementById("cf"), i: document.getElementById("i"), c: document.getElementById("c"), p: document.getElementById("p")};
+
+// The view is the centre of the map, with y increasing down like the tiles, and its zoom level
+var view = {x: 0, y: 0, z: 1};
+
+// unitsPerPixel is the size of a pixel on the plane at the current zoom level
+function unitsPerPixel() {
+	return 4 / (tileSize * Math.pow(2, view.z));
+}
+
+function fillSchemes(selected) {
+	inputs.cf.innerHTML = "";
+	["default"].concat(schemes[inputs.ff.value] || []).forEach(function(name) {
+		var option = document.createElement("option");
+		option.text = name;
+		option.selected = name === selected;
+		inputs.cf.add(option);
+	});
+}
+
+function query() {
+	var parts = ["cf=" + encodeURIComponent(inputs.cf.value), "i=" + encodeURIComponent(inputs.i.value)];
+	inputs.c.value.split(/[\s,]+/).filter(Boolean).forEach(function(c) { parts.push("c=" + encodeURIComponent(c)); });
+	inputs.p.value.split(/[\s;]+/).filter(Boolean).forEach(function(p) { parts.push("p=" + encodeURIComponent(p)); });
+	return parts.join("&");
+}
+
+// draw places the tiles covering the map, reusing those already there
+function draw() {
+	var width = map.clientWidth, height = map.clientHeight, tiles = Math.pow(2, view.z), upp = unitsPerPixel();
+	var left = (view.x + 2) / upp - width / 2, top = (view.y + 2) / upp - height / 2;
+	var q = query(), wanted = {};
+	for (var ty = Math.max(0, Math.floor(top / tileSize)); ty < Math.min(tiles, Math.ceil((top + height) / tileSize)); ty++) {
+		for (var tx = Math.max(0, Math.floor(left / tileSize)); tx < Math.min(tiles, Math.ceil((left + width) / tileSize)); tx++) {
+			var src = "tiles/" + encodeURIComponent(inputs.ff.value) + "/" + view.z + "/" + tx + "/" + ty + ".png?" + q;
+			var img = document.getElementById(src);
+			if (!img) {
+				img = document.createElement("img");
+				img.id = src;
+				img.src = src;
+				img.draggable = false;
+				map.appendChild(img);
+			}
+			img.style.left = Math.round(tx * tileSize - left) + "px";
+			img.style.top = Math.round(ty * tileSize - top) + "px";
+			wanted[src] = true;
+		}
+	}
+	Array.prototype.slice.call(map.children).forEach(function(img) {
+		if (!wanted[img.id]) map.removeChild(img);
+	});
+
+	// The CLI's y axis goes up, and its zoom factor fits 2 units into the shorter side
+	var cli = "romanesgo -ff=" + inputs.ff.value + " -cf=" + inputs.cf.value + " -i=" + inputs.i.value;
+	inputs.c.value.split(/[\s,]+/).filter(Boolean).forEach(function(c) { cli += " -c=" + c; });
+	inputs.p.value.split(/[\s;]+/).filter(Boolean).forEach(function(p) { cli += " -p=" + p; });
+	cli += " -x=" + view.x + " -y=" + -view.y + " -z=" + 2 / (Math.min(width, height) * upp) + " -w=" + width + " -h=" + height;
+	command.textContent = cli;
+
+	location.replace("#" + [inputs.ff.value, inputs.cf.value, inputs.i.value, inputs.c.value, inputs.p.value, view.x, view.y, view.z].map(encodeURIComponent).join("/"));
+}
+
+var drag = null;
+map.addEventListener("mousedown", function(e) {
+	drag = {x: e.clientX, y: e.clientY};
+	map.style.cursor = "grabbing";
+});
+window.addEventListener("mouseup", function() {
+	drag = null;
+	map.style.cursor = "";
+});
+window.addEventListener("mousemove", function(e) {
+	if (!drag) return;
+	view.x -= (e.clientX - drag.x) * unitsPerPixel();
+	view.y -= (e.clientY - drag.y) * unitsPerPixel();
+	drag = {x: e.clientX, y: e.clientY};
+	draw();
+});
+
+// Zooming keeps the point under the cursor where it is
+map.addEventListener("wheel", function(e) {
+	e.preventDefault();
+	var z = Math.min(maxZoom, Math.max(0, view.z + (e.deltaY < 0 ? 1 : -1)));
+	if (z === view.z) return;
+	var upp = unitsPerPixel(), rect = map.getBoundingClientRect();
+	var px = view.x + (e.clientX - rect.left - map.clientWidth / 2) * upp;
+	var py = view.y + (e.clientY - rect.top - map.clientHeight / 2) * upp;
+	var scale = Math.pow(2, view.z - z);
+	view.x = px - (px - view.x) * scale;
+	view.y = py - (py - view.y) * scale;
+	view.z = z;
+	draw();
+}, {passive: false});
+
+inputs.ff.addEventListener("change", function() { fillSchemes("default"); draw(); });
+["cf", "i", "c", "p"].forEach(function(name) { inputs[name].addEventListener("change", draw); });
+window.addEventListener("resize", draw);
+
+var hash = location.hash.slice(1).split("/").map(decodeURIComponent);
+if (hash.length === 8) {
+	inputs.ff.value = hash[0];
+	inputs.i.value = hash[2];
+	inputs.c.value = hash[3];
+	inputs.p.value = hash[4];
+	view = {x: +hash[5], y: +hash[6], z: +hash[7]};
+	fillSchemes(hash[1]);
+} else {
+	inputs.ff.value = "mandelbrot";
+	fillSchemes("default");
+}
+draw();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,32 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+// errNoRawMode is returned where terminals can't be put into raw mode
+var errNoRawMode = errors.New("interactive mode needs a unix terminal")
+
+// terminalSize returns the size of the terminal from $COLUMNS and $LINES, as
+// there's no asking the terminal itself here
+func terminalSize() (cols, rows, width, height int, err error) {
+	cols, colsErr := strconv.Atoi(os.Getenv("COLUMNS"))
+	rows, rowsErr := strconv.Atoi(os.Getenv("LINES"))
+	if colsErr != nil || rowsErr != nil {
+		return 0, 0, 0, 0, errors.New("the terminal's size is unknown")
+	}
+	return cols, rows, 0, 0, nil
+}
+
+// makeRaw can't put the terminal into raw mode here
+func makeRaw() (restore func(), err error) {
+	return nil, errNoRawMode
+}
+
+// notifyResize can't tell when the terminal is resized here
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"runtime"
+
+	"github.com/theteacat/romanesgo/lib"
+)
+
+// unroll resamples an exponential map strip (rendered with -em) into the
+// frames of a zoom video, e.g.
+//
+//	romanesgo -ff=mandelbrot -x=-0.7453 -y=0.1127 -em=1e5 -w=2000 -fn=strip.png
+//	romanesgo unroll -in=strip.png -frames=300 -w=600 -h=400 -fn=frames/%04d.png
+func unroll(args []string) {
+	fs := flag.NewFlagSet("unroll", flag.ExitOnError)
+	in := fs.String("in", "temp.png", "exponential map strip (png)")
+	frames := fs.Int("frames", 100, "number of frames")
+	depth := fs.Float64("depth", 0, "zoom factor of the last frame relative to the strip's, defaults to as deep as the strip goes")
+	width := fs.Int("w", 1000, "frame width")
+	height := fs.Int("h", 1000, "frame height")
+	routines := fs.Int("r", runtime.NumCPU(), "goroutines used")
+	fn := fs.String("fn", "frame%04d.png", `filename, either a numbered png pattern (e.g. "frames/%04d.png"), a .gif or an animated .png`)
+	var enc encodeFlags
+	enc.add(fs)
+	fs.Parse(args)
+
+	file, err := os.Open(*in)
+	fatal(err)
+	src, err := png.Decode(file)
+	file.Close()
+	fatal(err)
+
+	strip := image.NewNRGBA(src.Bounds())
+	draw.Draw(strip, strip.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	if *depth <= 0 {
+		*depth = lib.ExpMapDepth(strip.Bounds().Dx(), strip.Bounds().Dy())
+	}
+
+	out, err := newFrameWriter(*fn, *frames, *width, *height, enc)
+	fatal(err)
+
+	fmt.Print("\n\tStrip (in):\t\t", *in,
+		"\n\tFrames (frames):\t", *frames,
+		"\n\tDepth (depth):\t\t", *depth,
+		"\n\tFrame Width (w):\t", *width,
+		"\n\tFrame Height (h):\t", *height,
+		"\n\tRoutines (r):\t\t", *routines,
+		"\n\tFilename (fn):\t\t", *fn, "\n\n")
+
+	timeIt(func() {
+		for frame := 0; frame < *frames; frame++ {
+			// Each frame zooms in by the same factor
+			zoom := 1.0
+			if *frames > 1 {
+				zoom = math.Pow(*depth, float64(frame)/float64(*frames-1))
+			}
+
+			fatal(out.WriteFrame(frame, lib.UnrollExpMap(strip, *width, *height, *routines, zoom)))
+			fmt.Printf("\rFrame %d/%d", frame+1, *frames)
+		}
+		fmt.Println()
+		fatal(out.Close())
+	})
+}
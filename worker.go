@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"net/http"
+	"os"
+	"runtime"
+
+	"github.com/theteacat/romanesgo/server"
+)
+
+// worker renders regions of renders for a render given -workers, e.g.
+//
+//	romanesgo worker -addr=localhost:9001 &
+//	romanesgo worker -addr=localhost:9002 &
+//	romanesgo -ff=mandelbrot -w=25000 -h=25000 -workers=localhost:9001,localhost:9002
+func worker(args []string) {
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:9001", "address to listen on")
+	routines := fs.Int("r", runtime.NumCPU(), "goroutines used")
+	maxPixels := fs.Int("maxpixels", 16000000, "maximum pixels in a region")
+	fs.Parse(args)
+
+	fmt.Print("\n\tAddress (addr):\t\t", *addr,
+		"\n\tRoutines (r):\t\t", *routines,
+		"\n\tMax pixels (maxpixels):\t", *maxPixels, "\n\n")
+
+	fatal(http.ListenAndServe(*addr, server.NewWorker(*routines, *maxPixels)))
+}
+
+// distribute renders the spec across the workers, and saves it
+func distribute(spec server.RenderSpec, workers []string, fn string) {
+	fmt.Print("\tWorkers (workers):\t", len(workers),
+		"\n\tFilename (png) (fn):\t", fn, "\n\n")
+
+	coordinator := server.NewCoordinator(workers)
+	coordinator.Logf = func(format string, args ...interface{}) {
+		fmt.Printf("Worker "+format+"\n", args...)
+	}
+
+	newFile, err := os.Create(fn)
+	fatal(err)
+
+	timeIt(func() {
+		img, err := coordinator.Render(spec)
+		fatal(err)
+
+		err = png.Encode(newFile, img)
+		fatal(err)
+	})
+}